@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"volvomaster/internal/models"
+)
+
+// attachmentClient клиент для скачивания вложений из Telegram
+var attachmentClient = &http.Client{Timeout: 2 * time.Minute}
+
+// handleAttachment отдает файл вложения заявки: из хранилища, если файл был скачан ботом,
+// иначе через Telegram. Ссылка Telegram содержит токен бота, поэтому файл проксируется.
+func (s *AdminServer) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request, ok := s.getServiceRequest(w, r, r.URL.Query().Get("request_id"))
+	if !ok {
+		return
+	}
+
+	var attachment *models.Attachment
+	for i := range request.Attachments {
+		if request.Attachments[i].FileUniqueID == r.URL.Query().Get("file") {
+			attachment = &request.Attachments[i]
+			break
+		}
+	}
+	if attachment == nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := s.openAttachment(r.Context(), attachment)
+	if err != nil {
+		s.logger.Error("Ошибка получения вложения %s: %v", attachment.FileUniqueID, err)
+		http.Error(w, "Файл недоступен", http.StatusBadGateway)
+		return
+	}
+	defer body.Close()
+
+	// Тип файла объявляет клиент Telegram отправителя, поэтому ему нельзя доверять:
+	// документ text/html, открытый в браузере, выполнялся бы с правами сессии сотрудника
+	contentType, inline := attachmentContentType(attachment.MimeType)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	params := map[string]string{}
+	if attachment.FileName != "" {
+		params["filename"] = attachment.FileName
+	}
+	if header := mime.FormatMediaType(disposition, params); header != "" {
+		w.Header().Set("Content-Disposition", header)
+	} else {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+
+	if _, err := io.Copy(w, body); err != nil {
+		s.logger.Error("Ошибка передачи вложения: %v", err)
+	}
+}
+
+// attachmentContentType возвращает Content-Type для отдачи вложения и можно ли показать его в браузере.
+// Показываются только фото, видео и аудио, остальные файлы скачиваются.
+func attachmentContentType(declared string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return "application/octet-stream", false
+	}
+
+	// SVG - это документ со скриптами, а не просто картинка
+	if mediaType == "image/svg+xml" {
+		return mediaType, false
+	}
+	for _, prefix := range []string{"image/", "video/", "audio/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return mediaType, true
+		}
+	}
+	return mediaType, false
+}
+
+func (s *AdminServer) openAttachment(ctx context.Context, attachment *models.Attachment) (io.ReadCloser, error) {
+	if attachment.StorageKey != "" && s.blobs != nil {
+		body, err := s.blobs.Get(ctx, attachment.StorageKey)
+		if err == nil {
+			return body, nil
+		}
+		s.logger.Error("Вложение %s не получено из хранилища, пробуем Telegram: %v", attachment.StorageKey, err)
+	}
+
+	if s.files == nil {
+		return nil, errors.New("файл не сохранен в хранилище, а TELEGRAM_BOT_TOKEN не задан")
+	}
+
+	url, err := s.files.FileURL(attachment.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := attachmentClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Telegram вернул %s", resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/services"
+)
+
+const (
+	sessionCookieName = "admin_session"
+	csrfHeaderName    = "X-CSRF-Token"
+)
+
+type sessionContextKey struct{}
+
+// bootstrapAdmin создает первого менеджера из ADMIN_USERNAME/ADMIN_PASSWORD, если сотрудников еще нет
+func (s *AdminServer) bootstrapAdmin(ctx context.Context) {
+	count, err := s.dbService.CountAdmins(ctx)
+	if err != nil {
+		s.logger.Error("Ошибка проверки сотрудников: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	username, password := os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD")
+	if username == "" || password == "" {
+		s.logger.Info("Сотрудники не созданы: задайте ADMIN_USERNAME и ADMIN_PASSWORD для создания первого менеджера")
+		return
+	}
+
+	if _, err := s.dbService.CreateAdmin(ctx, username, password, models.RoleManager); err != nil {
+		s.logger.Error("Ошибка создания первого менеджера: %v", err)
+		return
+	}
+	s.logger.Info("Создан менеджер %s", username)
+}
+
+// requireRole пропускает запрос только с действующей сессией и достаточной ролью.
+// Для POST-запросов дополнительно проверяется CSRF-токен.
+func (s *AdminServer) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := s.sessionFromRequest(r)
+		if session == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !roleAllows(session.Role, role) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			token := r.Header.Get(csrfHeaderName)
+			if subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) != 1 {
+				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), sessionContextKey{}, session)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// sessionFromRequest получает сессию по cookie, nil если сессии нет или она истекла
+func (s *AdminServer) sessionFromRequest(r *http.Request) *models.AdminSession {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+
+	session, err := s.dbService.GetAdminSession(r.Context(), cookie.Value)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+// roleAllows проверяет, что роль have включает права роли need
+func roleAllows(have, need string) bool {
+	if have == models.RoleManager {
+		return true
+	}
+	return have == need
+}
+
+// actorFromRequest возвращает автора изменений для истории заявки
+func actorFromRequest(r *http.Request) string {
+	if session, ok := r.Context().Value(sessionContextKey{}).(*models.AdminSession); ok {
+		return "admin:" + session.Username
+	}
+	return "admin"
+}
+
+func (s *AdminServer) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	if s.sessionFromRequest(r) != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	http.ServeFile(w, r, "cmd/admin_interface/static/login.html")
+}
+
+func (s *AdminServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	admin, err := s.dbService.AuthenticateAdmin(r.Context(), strings.TrimSpace(req.Username), req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			s.logger.Info("Неудачная попытка входа: %s", req.Username)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.startSession(w, r, admin)
+}
+
+// handleTelegramLogin выполняет вход по одноразовому коду, выданному ботом командой /admin_login
+func (s *AdminServer) handleTelegramLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	login, err := s.dbService.ConsumeAdminLoginCode(r.Context(), req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLoginCode) {
+			s.logger.Info("Неудачная попытка входа по коду из Telegram")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	admin, err := s.dbService.GetOrCreateTelegramAdmin(r.Context(), login.TelegramID, s.telegramRole)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if login.Username != "" {
+		s.logger.Info("Вход через Telegram: @%s", login.Username)
+	}
+
+	s.startSession(w, r, admin)
+}
+
+// startSession создает сессию, выставляет cookie и отдает данные текущего сотрудника
+func (s *AdminServer) startSession(w http.ResponseWriter, r *http.Request, admin *models.Admin) {
+	session, err := s.dbService.CreateAdminSession(r.Context(), admin)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	s.logger.Info("Вход в админ-панель: %s (%s)", admin.Username, admin.Role)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func (s *AdminServer) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := s.dbService.DeleteAdminSession(r.Context(), cookie.Value); err != nil {
+			s.logger.Error("Ошибка удаления сессии: %v", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleMe отдает данные текущей сессии (роль и CSRF-токен для POST-запросов)
+func (s *AdminServer) handleMe(w http.ResponseWriter, r *http.Request) {
+	session := r.Context().Value(sessionContextKey{}).(*models.AdminSession)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func (s *AdminServer) handleAdmins(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		admins, err := s.dbService.GetAdmins(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(admins)
+
+	case "POST":
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Role     string `json:"role"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Username = strings.TrimSpace(req.Username)
+		if req.Username == "" || len(req.Password) < 8 {
+			http.Error(w, "Username and password (min 8 characters) are required", http.StatusBadRequest)
+			return
+		}
+		if req.Role != models.RoleManager && req.Role != models.RoleViewer {
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := s.dbService.CreateAdmin(r.Context(), req.Username, req.Password, req.Role); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		s.logger.Info("%s создал сотрудника %s (%s)", actorFromRequest(r), req.Username, req.Role)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -3,16 +3,20 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"volvomaster/internal/config"
 	"volvomaster/internal/database"
 	"volvomaster/internal/logger"
 	"volvomaster/internal/models"
+	"volvomaster/internal/notify"
 	"volvomaster/internal/services"
+	"volvomaster/internal/storage"
 
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -25,9 +29,35 @@ func getMongoURI() string {
 	return "mongodb://localhost:27017"
 }
 
+// getDatabaseBackend возвращает хранилище данных из DATABASE_BACKEND (по умолчанию "mongo")
+func getDatabaseBackend() string {
+	if backend := os.Getenv("DATABASE_BACKEND"); backend != "" {
+		return backend
+	}
+	return "mongo"
+}
+
+// getTelegramAdminRole возвращает роль, которую получает сотрудник при первом входе через Telegram
+func getTelegramAdminRole() string {
+	if role := os.Getenv("ADMIN_TELEGRAM_ROLE"); role != "" {
+		return role
+	}
+	return models.RoleViewer
+}
+
 type AdminServer struct {
-	dbService *services.DatabaseService
+	dbService services.Store
+	notifier  notify.Sender
+	files     fileSource
+	blobs     storage.BlobStore
 	logger    *logger.Logger
+
+	telegramRole string // роль сотрудника, впервые вошедшего через Telegram
+}
+
+// fileSource выдает ссылки на файлы вложений в Telegram
+type fileSource interface {
+	FileURL(fileID string) (string, error)
 }
 
 func main() {
@@ -37,29 +67,82 @@ func main() {
 		logger.Info("Файл .env не найден, используем системные переменные")
 	}
 
-	mongoURI := getMongoURI()
-	db, err := database.Connect(mongoURI)
-	if err != nil {
-		logger.Fatal("Ошибка подключения к MongoDB: %v", err)
+	server := &AdminServer{logger: logger, telegramRole: getTelegramAdminRole()}
+	if server.telegramRole != models.RoleManager && server.telegramRole != models.RoleViewer {
+		logger.Fatal("ADMIN_TELEGRAM_ROLE содержит неверное значение: %s", server.telegramRole)
+	}
+
+	switch backend := getDatabaseBackend(); backend {
+	case "memory":
+		// Хранилище в памяти принадлежит процессу: заявок и расписания бота админ-панель не увидит
+		logger.Fatal("Админ-панель работает только с MongoDB: при DATABASE_BACKEND=memory у нее нет общих данных с ботом")
+
+	case "mongo":
+		db, err := database.Connect(getMongoURI())
+		if err != nil {
+			logger.Fatal("Ошибка подключения к MongoDB: %v", err)
+		}
+		defer db.Disconnect(context.Background())
+
+		dbService := services.NewDatabaseService(db)
+		if err := dbService.EnsureAdminIndexes(context.Background()); err != nil {
+			logger.Error("Ошибка создания индексов админ-панели: %v", err)
+		}
+		server.dbService = dbService
+
+	default:
+		logger.Fatal("Неизвестное хранилище данных DATABASE_BACKEND=%s", backend)
+	}
+
+	// Отправка уведомлений клиентам через того же Telegram-бота
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		sender, err := notify.NewTelegramSenderFromToken(token)
+		if err != nil {
+			logger.Error("Уведомления клиентам недоступны: %v", err)
+		} else {
+			server.notifier = sender
+			server.files = sender
+		}
+	} else {
+		logger.Info("TELEGRAM_BOT_TOKEN не задан, уведомления клиентам отключены")
 	}
-	defer db.Disconnect(context.Background())
 
-	dbService := services.NewDatabaseService(db)
-	server := &AdminServer{
-		dbService: dbService,
-		logger:    logger,
+	// Хранилище вложений (то же, что у бота)
+	blobs, err := storage.New(config.LoadStorage())
+	if err != nil {
+		logger.Error("Хранилище вложений недоступно: %v", err)
+	} else {
+		server.blobs = blobs
 	}
 
+	server.bootstrapAdmin(context.Background())
+
 	// Статические файлы
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("cmd/admin_interface/static"))))
 
-	// Маршруты
+	// Вход и выход
+	http.HandleFunc("/login", server.handleLoginPage)
+	http.HandleFunc("/api/login", server.handleLogin)
+	http.HandleFunc("/api/login-telegram", server.handleTelegramLogin)
+	http.HandleFunc("/api/logout", server.requireRole(models.RoleViewer, server.handleLogout))
+	http.HandleFunc("/api/me", server.requireRole(models.RoleViewer, server.handleMe))
+	http.HandleFunc("/api/admins", server.requireRole(models.RoleManager, server.handleAdmins))
+
+	// Маршруты только для чтения
 	http.HandleFunc("/", server.handleIndex)
-	http.HandleFunc("/api/dates", server.handleDates)
-	http.HandleFunc("/api/add-date", server.handleAddDate)
-	http.HandleFunc("/api/delete-date", server.handleDeleteDate)
-	http.HandleFunc("/api/requests", server.handleRequests)
-	http.HandleFunc("/api/update-slots", server.handleUpdateSlots)
+	http.HandleFunc("/api/dates", server.requireRole(models.RoleViewer, server.handleDates))
+	http.HandleFunc("/api/requests", server.requireRole(models.RoleViewer, server.handleRequests))
+	http.HandleFunc("/api/status-transitions", server.requireRole(models.RoleViewer, server.handleStatusTransitions))
+	http.HandleFunc("/api/notification-templates", server.requireRole(models.RoleViewer, server.handleNotificationTemplates))
+	http.HandleFunc("/api/attachment", server.requireRole(models.RoleViewer, server.handleAttachment))
+
+	// Маршруты для изменения расписания и заявок
+	http.HandleFunc("/api/add-date", server.requireRole(models.RoleManager, server.handleAddDate))
+	http.HandleFunc("/api/delete-date", server.requireRole(models.RoleManager, server.handleDeleteDate))
+	http.HandleFunc("/api/update-slots", server.requireRole(models.RoleManager, server.handleUpdateSlots))
+	http.HandleFunc("/api/request-status", server.requireRole(models.RoleManager, server.handleRequestStatus))
+	http.HandleFunc("/api/request-note", server.requireRole(models.RoleManager, server.handleRequestNote))
+	http.HandleFunc("/api/request-notify", server.requireRole(models.RoleManager, server.handleRequestNotify))
 
 	port := ":8080"
 	logger.Info("Админ-панель запущена на http://localhost%s", port)
@@ -67,6 +150,17 @@ func main() {
 }
 
 func (s *AdminServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Без действующей сессии отправляем на страницу входа
+	if s.sessionFromRequest(r) == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
 	// Читаем HTML файл
 	htmlBytes, err := os.ReadFile("cmd/admin_interface/static/index.html")
 	if err != nil {
@@ -84,7 +178,7 @@ func (s *AdminServer) handleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *AdminServer) handleDates(w http.ResponseWriter, r *http.Request) {
-	dates, err := s.dbService.GetAvailableDates(context.Background())
+	dates, err := s.dbService.GetAvailableDates(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -113,7 +207,7 @@ func (s *AdminServer) handleAddDate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	var dates []time.Time
 
 	switch req.Type {
@@ -214,15 +308,12 @@ func (s *AdminServer) handleDeleteDate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получаем дату и деактивируем её
-	date, err := s.dbService.GetAvailableDateByID(context.Background(), id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	date.IsActive = false
-	if err := s.dbService.SaveAvailableDate(context.Background(), date); err != nil {
+	// Деактивируем дату, не перезаписывая слоты, которые бот мог забронировать тем временем
+	if err := s.dbService.SetAvailableDateActive(r.Context(), id, false); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			http.Error(w, "Date not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -231,7 +322,7 @@ func (s *AdminServer) handleDeleteDate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *AdminServer) handleRequests(w http.ResponseWriter, r *http.Request) {
-	requests, err := s.dbService.GetServiceRequests(context.Background(), nil)
+	requests, err := s.dbService.GetServiceRequests(r.Context(), services.RequestFilter{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -267,21 +358,168 @@ func (s *AdminServer) handleUpdateSlots(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Получаем дату
-	date, err := s.dbService.GetAvailableDateByID(context.Background(), id)
+	date, err := s.dbService.GetAvailableDateByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Обновляем слоты
 	for _, slotUpdate := range req.Slots {
-		if slotUpdate.Index < len(date.TimeSlots) {
-			date.TimeSlots[slotUpdate.Index].IsBooked = slotUpdate.IsBooked
+		if slotUpdate.Index < 0 || slotUpdate.Index >= len(date.TimeSlots) {
+			http.Error(w, "Invalid slot index", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Меняем только слоты, которые сотрудник переключил; каждый - отдельным условным обновлением,
+	// чтобы не затереть бронь, сделанную ботом после чтения даты
+	for _, slotUpdate := range req.Slots {
+		slot := date.TimeSlots[slotUpdate.Index]
+		if slot.IsBooked == slotUpdate.IsBooked {
+			continue
+		}
+		// Бронь заявки снимается отменой или переносом заявки, иначе заявка осталась бы записанной на чужой слот
+		if !slot.RequestID.IsZero() {
+			http.Error(w, fmt.Sprintf("Слот %s занят заявкой, отмените или перенесите ее", slot.Time), http.StatusConflict)
+			return
+		}
+
+		if err := s.dbService.SetTimeSlotBooked(r.Context(), id, slotUpdate.Index, slotUpdate.IsBooked); err != nil {
+			if errors.Is(err, services.ErrSlotUnavailable) {
+				http.Error(w, fmt.Sprintf("Слот %s только что заняла заявка клиента", slot.Time), http.StatusConflict)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *AdminServer) handleStatusTransitions(w http.ResponseWriter, r *http.Request) {
+	statuses := []string{
+		models.StatusDraft, models.StatusBooked, models.StatusConfirmed, models.StatusInService,
+		models.StatusDone, models.StatusCancelled, models.StatusNoShow,
+	}
+
+	transitions := make(map[string][]string)
+	for _, status := range statuses {
+		transitions[status] = adminTransitions(status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transitions)
+}
+
+// adminTransitions статусы, в которые сотрудник может перевести заявку из статуса from.
+// В booked заявку переводит только бот при выборе времени клиентом: статус без
+// забронированного слота и времени записи сломал бы напоминания и /my.
+func adminTransitions(from string) []string {
+	var allowed []string
+	for _, status := range services.AllowedTransitions(from) {
+		if status != models.StatusBooked {
+			allowed = append(allowed, status)
+		}
+	}
+	return allowed
+}
+
+func (s *AdminServer) handleNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notify.Templates)
+}
+
+func (s *AdminServer) handleRequestStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		Reason   string `json:"reason"`
+		Notify   bool   `json:"notify"`
+		Template string `json:"template"`
+		Message  string `json:"message"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Status == models.StatusBooked {
+		http.Error(w, "Статус booked устанавливается только при записи клиента на время", http.StatusConflict)
+		return
+	}
+
+	ctx := r.Context()
+	request, ok := s.getServiceRequest(w, r, req.ID)
+	if !ok {
+		return
+	}
+
+	var err error
+	if req.Status == models.StatusCancelled {
+		err = s.dbService.CancelServiceRequest(ctx, request, actorFromRequest(r), req.Reason)
+	} else {
+		err = s.dbService.TransitionServiceRequest(ctx, request, req.Status, actorFromRequest(r), req.Reason)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTransition) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		}
+		// Статус мог сохраниться, а ошибка относиться к освобождению слота
+		if request.Status != req.Status {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.logger.Error("Ошибка освобождения слота заявки %s: %v", request.ID.Hex(), err)
+	}
+
+	if req.Notify {
+		if err := s.notifyCustomer(ctx, request, actorFromRequest(r), req.Template, req.Message); err != nil {
+			s.logger.Error("Ошибка отправки уведомления по заявке %s: %v", request.ID.Hex(), err)
+			http.Error(w, "Статус изменен, но уведомление не отправлено: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+func (s *AdminServer) handleRequestNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Сохраняем обновленную дату
-	if err := s.dbService.SaveAvailableDate(context.Background(), date); err != nil {
+	var req struct {
+		ID   string `json:"id"`
+		Text string `json:"text"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Text) == "" {
+		http.Error(w, "Text is required", http.StatusBadRequest)
+		return
+	}
+
+	request, ok := s.getServiceRequest(w, r, req.ID)
+	if !ok {
+		return
+	}
+
+	note := models.RequestNote{Author: actorFromRequest(r), Text: strings.TrimSpace(req.Text)}
+	if err := s.dbService.AddServiceRequestNote(r.Context(), request.ID, note); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -289,6 +527,84 @@ func (s *AdminServer) handleUpdateSlots(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s *AdminServer) handleRequestNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		Template string `json:"template"`
+		Message  string `json:"message"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	request, ok := s.getServiceRequest(w, r, req.ID)
+	if !ok {
+		return
+	}
+
+	if err := s.notifyCustomer(r.Context(), request, actorFromRequest(r), req.Template, req.Message); err != nil {
+		s.logger.Error("Ошибка отправки уведомления по заявке %s: %v", request.ID.Hex(), err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// getServiceRequest получает заявку по ID из запроса и пишет ошибку в ответ, если это не удалось
+func (s *AdminServer) getServiceRequest(w http.ResponseWriter, r *http.Request, rawID string) (*models.ServiceRequest, bool) {
+	id, err := primitive.ObjectIDFromHex(rawID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	request, err := s.dbService.GetServiceRequest(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			http.Error(w, "Request not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	return request, true
+}
+
+// notifyCustomer отправляет клиенту сообщение (свой текст или шаблон) и фиксирует его в заметках заявки
+func (s *AdminServer) notifyCustomer(ctx context.Context, request *models.ServiceRequest, actor, templateKey, message string) error {
+	if s.notifier == nil {
+		return errors.New("уведомления отключены: не задан TELEGRAM_BOT_TOKEN")
+	}
+
+	text := strings.TrimSpace(message)
+	if text == "" {
+		rendered, err := notify.Render(templateKey, request)
+		if err != nil {
+			return err
+		}
+		text = rendered
+	}
+
+	if err := s.notifier.Notify(request.ChatID, text); err != nil {
+		return err
+	}
+
+	note := models.RequestNote{Author: actor, Text: "Отправлено уведомление клиенту: " + text}
+	if err := s.dbService.AddServiceRequestNote(ctx, request.ID, note); err != nil {
+		s.logger.Error("Ошибка сохранения заметки об уведомлении: %v", err)
+	}
+	return nil
+}
+
 // parseTime парсит строку времени в формате "HH:MM"
 func parseTime(timeStr string) (hour, minute int, err error) {
 	_, err = fmt.Sscanf(timeStr, "%d:%d", &hour, &minute)
@@ -1,12 +1,89 @@
-// Загружаем даты при загрузке страницы
+// Текущая сессия сотрудника (роль и CSRF-токен)
+let currentSession = null;
+
+// Загружаем данные сессии, а затем даты и заявки при загрузке страницы
 window.onload = function() {
-    loadDates();
-    loadRequests();
+    apiGet('/api/me').then(session => {
+        currentSession = session;
+        document.getElementById('currentUser').textContent = session.username + ' (' + formatRole(session.role) + ')';
+        document.body.classList.add('role-' + session.role);
+
+        loadDates();
+        loadRequests();
+        loadRequestMeta();
+        if (session.role === 'manager') {
+            loadAdmins();
+        }
+    });
 };
 
+// apiGet выполняет GET-запрос и отправляет на страницу входа, если сессия истекла
+function apiGet(url) {
+    return fetch(url).then(response => {
+        if (response.status === 401) {
+            window.location.href = '/login';
+            return Promise.reject(new Error('Unauthorized'));
+        }
+        return response.json();
+    });
+}
+
+// apiPost выполняет POST-запрос с JSON-телом и CSRF-токеном текущей сессии
+function apiPost(url, body) {
+    return fetch(url, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+            'X-CSRF-Token': currentSession ? currentSession.csrf_token : ''
+        },
+        body: JSON.stringify(body || {})
+    }).then(response => {
+        if (response.status === 401) {
+            window.location.href = '/login';
+        }
+        return response;
+    });
+}
+
+function formatRole(role) {
+    return role === 'manager' ? 'менеджер' : 'просмотр';
+}
+
+// escapeHtml экранирует текст перед вставкой в innerHTML.
+// Все, что пришло от клиентов бота или сотрудников (имена, ответы, подписи, заметки), выводится только через нее.
+function escapeHtml(value) {
+    if (value === undefined || value === null) {
+        return '';
+    }
+    return String(value)
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;')
+        .replace(/'/g, '&#39;');
+}
+
+function logout() {
+    apiPost('/api/logout').then(() => {
+        window.location.href = '/login';
+    });
+}
+
+// Заявки, загруженные в таблицу, по ID
+let requestsById = {};
+// Допустимые переходы статусов и шаблоны уведомлений
+let statusTransitions = {};
+let notificationTemplates = [];
+
+function loadRequestMeta() {
+    apiGet('/api/status-transitions')
+        .then(data => { statusTransitions = data; });
+    apiGet('/api/notification-templates')
+        .then(data => { notificationTemplates = data; });
+}
+
 function loadDates() {
-    fetch('/api/dates')
-        .then(response => response.json())
+    apiGet('/api/dates')
         .then(data => {
             const grid = document.getElementById('datesGrid');
             grid.innerHTML = '';
@@ -29,12 +106,12 @@ function loadDates() {
                     slotsHtml += '<span class="' + slotClass + '">' + slot.time + '</span>';
                 });
                 
-                card.innerHTML = '<input type="checkbox" class="checkbox" onchange="toggleDateSelection(this)">' +
+                card.innerHTML = '<input type="checkbox" class="checkbox manager-only" onchange="toggleDateSelection(this)">' +
                                '<div><strong>' + dateStr + ' (' + weekday + ')</strong></div>' +
                                '<div class="time-slots">Свободных слотов: ' + timeSlots + ' из ' + totalSlots + '</div>' +
                                '<div class="edit-slots">' + slotsHtml + '</div>' +
-                               '<button class="btn btn-primary" onclick="editSlots(\'' + date.id + '\')">Редактировать слоты</button>' +
-                               '<button class="btn btn-danger" onclick="deleteDate(\'' + date.id + '\')">Удалить</button>';
+                               '<button class="btn btn-primary manager-only" onclick="editSlots(\'' + date.id + '\')">Редактировать слоты</button>' +
+                               '<button class="btn btn-danger manager-only" onclick="deleteDate(\'' + date.id + '\')">Удалить</button>';
                 
                 grid.appendChild(card);
             });
@@ -42,19 +119,11 @@ function loadDates() {
 }
 
 function addNextWeek() {
-    fetch('/api/add-date', {
-        method: 'POST',
-        headers: {'Content-Type': 'application/json'},
-        body: JSON.stringify({type: 'week'})
-    }).then(() => loadDates());
+    apiPost('/api/add-date', {type: 'week'}).then(() => loadDates());
 }
 
 function addNextMonth() {
-    fetch('/api/add-date', {
-        method: 'POST',
-        headers: {'Content-Type': 'application/json'},
-        body: JSON.stringify({type: 'month'})
-    }).then(() => loadDates());
+    apiPost('/api/add-date', {type: 'month'}).then(() => loadDates());
 }
 
 function addCustomDate() {
@@ -73,16 +142,12 @@ function addCustomDate() {
         return;
     }
     
-    fetch('/api/add-date', {
-        method: 'POST',
-        headers: {'Content-Type': 'application/json'},
-        body: JSON.stringify({
-            type: 'custom', 
-            date: date,
-            startTime: startTime,
-            endTime: endTime,
-            interval: interval
-        })
+    apiPost('/api/add-date', {
+        type: 'custom', 
+        date: date,
+        startTime: startTime,
+        endTime: endTime,
+        interval: interval
     }).then(() => {
         loadDates();
         document.getElementById('customDate').value = '';
@@ -91,11 +156,7 @@ function addCustomDate() {
 
 function deleteDate(id) {
     if (confirm('Удалить эту дату?')) {
-        fetch('/api/delete-date', {
-            method: 'POST',
-            headers: {'Content-Type': 'application/json'},
-            body: JSON.stringify({id: id})
-        }).then(() => loadDates());
+        apiPost('/api/delete-date', {id: id}).then(() => loadDates());
     }
 }
 
@@ -137,11 +198,7 @@ function deleteSelected() {
         const id = card.dataset.id;
         
         deletePromises.push(
-            fetch('/api/delete-date', {
-                method: 'POST',
-                headers: {'Content-Type': 'application/json'},
-                body: JSON.stringify({id: id})
-            })
+            apiPost('/api/delete-date', {id: id})
         );
     });
     
@@ -152,8 +209,7 @@ function deleteSelected() {
 }
 
 function editSlots(dateId) {
-    fetch('/api/dates')
-        .then(response => response.json())
+    apiGet('/api/dates')
         .then(dates => {
             const date = dates.find(d => d.id === dateId);
             if (!date) {
@@ -169,9 +225,11 @@ function editSlots(dateId) {
             
             date.time_slots.forEach((slot, index) => {
                 const checked = slot.is_booked ? 'checked' : '';
+                // Бронь заявки снимается только отменой или переносом самой заявки
+                const owned = hasRequest(slot);
                 slotsHtml += '<div style="margin: 5px 0;">' +
-                           '<input type="checkbox" id="slot_' + index + '" ' + checked + '>' +
-                           '<label for="slot_' + index + '">' + slot.time + '</label>' +
+                           '<input type="checkbox" id="slot_' + index + '" ' + checked + (owned ? ' disabled' : '') + '>' +
+                           '<label for="slot_' + index + '">' + escapeHtml(slot.time) + (owned ? ' (заявка клиента)' : '') + '</label>' +
                            '</div>';
             });
             
@@ -195,17 +253,20 @@ function saveSlots(dateId) {
         });
     });
     
-    fetch('/api/update-slots', {
-        method: 'POST',
-        headers: {'Content-Type': 'application/json'},
-        body: JSON.stringify({
-            dateId: dateId,
-            slots: slots
-        })
-    }).then(() => {
+    apiPost('/api/update-slots', {
+        dateId: dateId,
+        slots: slots
+    }).then(response => {
+        if (!response.ok) {
+            return response.text().then(text => alert('Ошибка: ' + text));
+        }
         closeModal();
-        loadDates();
-    });
+    }).then(() => loadDates());
+}
+
+// hasRequest проверяет, забронирован ли слот заявкой клиента
+function hasRequest(slot) {
+    return Boolean(slot.request_id) && slot.request_id !== '000000000000000000000000';
 }
 
 function closeModal() {
@@ -213,8 +274,7 @@ function closeModal() {
 }
 
 function loadRequests() {
-    fetch('/api/requests')
-        .then(response => response.json())
+    apiGet('/api/requests')
         .then(data => {
             const container = document.getElementById('requestsList');
             
@@ -224,9 +284,11 @@ function loadRequests() {
             }
             
             let html = '<table class="requests-table">';
-            html += '<tr><th>Дата создания</th><th>Имя</th><th>Контакт</th><th>Модель</th><th>Проблема</th><th>Время записи</th><th>Статус</th></tr>';
+            html += '<tr><th>Дата создания</th><th>Имя</th><th>Контакт</th><th>Модель</th><th>Проблема</th><th>Время записи</th><th>Статус</th><th>История</th><th>Действия</th></tr>';
             
+            requestsById = {};
             data.forEach(request => {
+                requestsById[request.id] = request;
                 const date = new Date(request.created_at).toLocaleDateString('ru-RU');
                 const appointmentDate = request.appointment_date ? 
                     new Date(request.appointment_date).toLocaleDateString('ru-RU') + ' ' + 
@@ -235,16 +297,246 @@ function loadRequests() {
                 
                 html += '<tr>' +
                        '<td>' + date + '</td>' +
-                       '<td>' + request.name + '</td>' +
-                       '<td>' + request.contact + '</td>' +
-                       '<td>' + request.volvo_model + ' ' + request.year + '</td>' +
-                       '<td>' + request.problem + '</td>' +
+                       '<td>' + escapeHtml(request.name) + '</td>' +
+                       '<td>' + escapeHtml(request.contact) + '</td>' +
+                       '<td>' + escapeHtml(request.volvo_model + ' ' + request.year) + '</td>' +
+                       '<td>' + escapeHtml(request.problem) + '</td>' +
                        '<td>' + appointmentDate + '</td>' +
-                       '<td>' + request.status + '</td>' +
+                       '<td>' + formatStatus(request.status) + '</td>' +
+                       '<td>' + formatStatusHistory(request.status_history) + '</td>' +
+                       '<td><button class="btn btn-primary manager-only" onclick="openRequestModal(\'' + request.id + '\')">Управление</button></td>' +
                        '</tr>';
             });
             
             html += '</table>';
             container.innerHTML = html;
         });
-} 
\ No newline at end of file
+} 
+
+const STATUS_LABELS = {
+    draft: '📝 Заполняется',
+    booked: '📅 Записан',
+    confirmed: '✅ Подтвержден клиентом',
+    in_service: '🔧 В работе',
+    done: '🏁 Выполнена',
+    cancelled: '❌ Отменена',
+    no_show: '🚫 Не приехал'
+};
+
+function formatStatus(status) {
+    return STATUS_LABELS[status] || escapeHtml(status);
+}
+
+function formatStatusHistory(history) {
+    if (!history || history.length === 0) {
+        return '—';
+    }
+
+    let html = '<details><summary>' + history.length + ' изм.</summary><ul class="status-history">';
+    history.forEach(change => {
+        const at = new Date(change.at).toLocaleString('ru-RU');
+        html += '<li>' + at + ': ' + (change.from ? formatStatus(change.from) + ' → ' : '') + formatStatus(change.to) +
+                ' <em>(' + escapeHtml(change.actor) + (change.reason ? ', ' + escapeHtml(change.reason) : '') + ')</em></li>';
+    });
+    html += '</ul></details>';
+    return html;
+}
+
+function openRequestModal(requestId) {
+    const request = requestsById[requestId];
+    if (!request) {
+        alert('Заявка не найдена!');
+        return;
+    }
+
+    const nextStatuses = statusTransitions[request.status] || [];
+    let html = '<div><strong>' + escapeHtml(request.name) + '</strong>, ' + escapeHtml(request.volvo_model + ' ' + request.year) + '</div>';
+    html += '<div>Текущий статус: ' + formatStatus(request.status) + '</div>';
+
+    html += '<h4>Ответы анкеты</h4>';
+    html += formatAnswers(request.answers);
+    if (request.approved_at) {
+        html += '<p><em>Подтверждено клиентом ' + new Date(request.approved_at).toLocaleString('ru-RU') + '</em></p>';
+    }
+
+    html += '<h4>Вложения</h4>';
+    html += formatAttachments(request);
+
+    html += '<h4>Изменить статус</h4>';
+    if (nextStatuses.length === 0) {
+        html += '<p>Заявка в конечном статусе</p>';
+    } else {
+        html += '<select id="newStatus">';
+        nextStatuses.forEach(status => {
+            html += '<option value="' + status + '">' + formatStatus(status) + '</option>';
+        });
+        html += '</select> ';
+        html += '<input type="text" id="statusReason" placeholder="Причина (необязательно)">';
+        html += '<div><label><input type="checkbox" id="statusNotify"> Уведомить клиента</label></div>';
+    }
+
+    html += '<h4>Уведомление клиенту</h4>';
+    html += '<select id="notifyTemplate"><option value="">Свой текст</option>';
+    notificationTemplates.forEach(template => {
+        html += '<option value="' + escapeHtml(template.key) + '">' + escapeHtml(template.title) + '</option>';
+    });
+    html += '</select>';
+    html += '<textarea id="notifyMessage" rows="3" style="width: 100%;" placeholder="Текст сообщения (если не выбран шаблон)"></textarea>';
+
+    html += '<h4>Заметки</h4>';
+    html += formatNotes(request.notes);
+    html += '<textarea id="noteText" rows="2" style="width: 100%;" placeholder="Внутренняя заметка"></textarea>';
+
+    html += '<div style="margin-top: 15px;">';
+    if (nextStatuses.length > 0) {
+        html += '<button class="btn btn-primary" onclick="saveRequestStatus(\'' + requestId + '\')">Сменить статус</button>';
+    }
+    html += '<button class="btn btn-success" onclick="sendRequestNotification(\'' + requestId + '\')">Отправить уведомление</button>';
+    html += '<button class="btn btn-primary" onclick="addRequestNote(\'' + requestId + '\')">Добавить заметку</button>';
+    html += '<button class="btn btn-danger" onclick="closeRequestModal()">Закрыть</button>';
+    html += '</div>';
+
+    document.getElementById('requestModalContent').innerHTML = html;
+    document.getElementById('requestModal').style.display = 'block';
+}
+
+function closeRequestModal() {
+    document.getElementById('requestModal').style.display = 'none';
+}
+
+function formatAnswers(answers) {
+    const keys = Object.keys(answers || {});
+    if (keys.length === 0) {
+        return '<p>Ответов нет</p>';
+    }
+
+    let html = '<ul class="status-history">';
+    keys.forEach(key => {
+        html += '<li><em>' + escapeHtml(key) + ':</em> ' + escapeHtml(answers[key]) + '</li>';
+    });
+    html += '</ul>';
+    return html;
+}
+
+const ATTACHMENT_LABELS = {
+    photo: '🖼 Фото',
+    video: '🎬 Видео',
+    video_note: '🎬 Видеосообщение',
+    voice: '🎤 Голосовое сообщение',
+    audio: '🎵 Аудио',
+    document: '📄 Документ'
+};
+
+function formatAttachments(request) {
+    if (!request.attachments || request.attachments.length === 0) {
+        return '<p>Вложений нет</p>';
+    }
+
+    let html = '<ul class="status-history">';
+    request.attachments.forEach(attachment => {
+        const url = '/api/attachment?request_id=' + encodeURIComponent(request.id) + '&file=' + encodeURIComponent(attachment.file_unique_id);
+        let title = ATTACHMENT_LABELS[attachment.type] || escapeHtml(attachment.type);
+        if (attachment.file_name) {
+            title += ' ' + escapeHtml(attachment.file_name);
+        }
+        html += '<li><a href="' + escapeHtml(url) + '" target="_blank" rel="noopener">' + title + '</a>';
+        if (attachment.caption) {
+            html += ': ' + escapeHtml(attachment.caption);
+        }
+        html += '</li>';
+    });
+    html += '</ul>';
+    return html;
+}
+
+function formatNotes(notes) {
+    if (!notes || notes.length === 0) {
+        return '<p>Заметок пока нет</p>';
+    }
+
+    let html = '<ul class="status-history">';
+    notes.forEach(note => {
+        html += '<li>' + new Date(note.created_at).toLocaleString('ru-RU') + ' <em>(' + escapeHtml(note.author) + ')</em>: ' + escapeHtml(note.text) + '</li>';
+    });
+    html += '</ul>';
+    return html;
+}
+
+// postRequestAction отправляет действие по заявке и обновляет список
+function postRequestAction(url, body) {
+    apiPost(url, body).then(response => {
+        if (!response.ok) {
+            return response.text().then(text => alert('Ошибка: ' + text));
+        }
+        closeRequestModal();
+    }).then(() => loadRequests());
+}
+
+function saveRequestStatus(requestId) {
+    postRequestAction('/api/request-status', {
+        id: requestId,
+        status: document.getElementById('newStatus').value,
+        reason: document.getElementById('statusReason').value,
+        notify: document.getElementById('statusNotify').checked,
+        template: document.getElementById('notifyTemplate').value,
+        message: document.getElementById('notifyMessage').value
+    });
+}
+
+function sendRequestNotification(requestId) {
+    const template = document.getElementById('notifyTemplate').value;
+    const message = document.getElementById('notifyMessage').value;
+    if (!template && !message.trim()) {
+        alert('Выберите шаблон или введите текст сообщения!');
+        return;
+    }
+
+    postRequestAction('/api/request-notify', {id: requestId, template: template, message: message});
+}
+
+function addRequestNote(requestId) {
+    const text = document.getElementById('noteText').value;
+    if (!text.trim()) {
+        alert('Введите текст заметки!');
+        return;
+    }
+
+    postRequestAction('/api/request-note', {id: requestId, text: text});
+}
+
+function loadAdmins() {
+    apiGet('/api/admins')
+        .then(data => {
+            const container = document.getElementById('adminsList');
+            let html = '<table class="requests-table"><tr><th>Логин</th><th>Роль</th><th>Создан</th></tr>';
+            data.forEach(admin => {
+                html += '<tr>' +
+                       '<td>' + escapeHtml(admin.username) + '</td>' +
+                       '<td>' + formatRole(admin.role) + '</td>' +
+                       '<td>' + new Date(admin.created_at).toLocaleDateString('ru-RU') + '</td>' +
+                       '</tr>';
+            });
+            html += '</table>';
+            container.innerHTML = html;
+        });
+}
+
+function addAdmin() {
+    const username = document.getElementById('newAdminUsername').value;
+    const password = document.getElementById('newAdminPassword').value;
+    const role = document.getElementById('newAdminRole').value;
+
+    if (!username || password.length < 8) {
+        alert('Укажите логин и пароль не короче 8 символов!');
+        return;
+    }
+
+    apiPost('/api/admins', {username: username, password: password, role: role}).then(response => {
+        if (!response.ok) {
+            return response.text().then(text => alert('Ошибка: ' + text));
+        }
+        document.getElementById('newAdminUsername').value = '';
+        document.getElementById('newAdminPassword').value = '';
+        loadAdmins();
+    });
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"volvomaster/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleAdminLoginCommand выдает сотруднику из списка ADMIN_TELEGRAM_IDS одноразовый код входа в админ-панель
+func (b *Bot) handleAdminLoginCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	if !b.config.IsAdminTelegramID(userID) {
+		b.logger.Info("Отказано в коде входа в админ-панель пользователю %d", userID)
+		b.sendMessage(chatID, "Команда доступна только сотрудникам сервисного центра.")
+		return
+	}
+
+	// Код выдаем только в личном чате, чтобы его не увидели посторонние
+	if !message.Chat.IsPrivate() {
+		b.sendMessage(chatID, "Запросите код входа в личном чате с ботом.")
+		return
+	}
+
+	code, err := b.dbService.CreateAdminLoginCode(ctx, userID, message.From.UserName)
+	if err != nil {
+		b.logger.Error("Ошибка создания кода входа: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	loginURL := strings.TrimRight(b.config.AdminURL, "/") + "/login?code=" + url.QueryEscape(code)
+	b.sendMessage(chatID, fmt.Sprintf(`🔐 Код входа в админ-панель: %s
+
+Код одноразовый и действует %d мин.
+Ссылка для входа: %s`, code, int(services.AdminLoginCodeTTL.Minutes()), loginURL))
+
+	b.logger.Info("Выдан код входа в админ-панель пользователю %d", userID)
+}
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// handleMyCommand показывает пользователю его предстоящие записи
+func (b *Bot) handleMyCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	filter := services.RequestFilter{
+		UserID:          message.From.ID,
+		Statuses:        []string{models.StatusBooked, models.StatusConfirmed},
+		AppointmentFrom: time.Now().Truncate(24 * time.Hour),
+	}
+
+	requests, err := b.dbService.GetServiceRequests(ctx, filter)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявок пользователя: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	if len(requests) == 0 {
+		b.sendMessage(chatID, "У вас нет предстоящих записей. Нажмите /start для создания новой заявки.")
+		return
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].AppointmentDate.Before(requests[j].AppointmentDate)
+	})
+
+	for _, request := range requests {
+		text := fmt.Sprintf(`📅 %s
+🚗 Модель: %s %s
+🔧 Проблема: %s`,
+			request.AppointmentDate.Format("02.01.2006 в 15:04"),
+			request.VolvoModel, request.Year, request.Problem)
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔁 Перенести", fmt.Sprintf("reschedule_%s", request.ID.Hex())),
+				tgbotapi.NewInlineKeyboardButtonData("❌ Отменить", fmt.Sprintf("cancelreq_%s", request.ID.Hex())),
+			),
+		)
+
+		if err := b.messenger.SendKeyboard(chatID, text, keyboard); err != nil {
+			b.logger.Error("Ошибка отправки сообщения: %v", err)
+		}
+	}
+}
+
+// handleRescheduleRequest переводит пользователя к выбору новой даты для существующей записи
+func (b *Bot) handleRescheduleRequest(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	request, ok := b.getOwnBookedRequest(ctx, callback, "reschedule_")
+	if !ok {
+		return
+	}
+
+	// Незаконченный черновик больше не нужен: сессия переходит к переносу записи
+	draftCancelled := false
+	if !session.RequestID.IsZero() && session.RequestID != request.ID {
+		draft, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+		if err == nil && draft.Status == models.StatusDraft {
+			if err := b.dbService.TransitionServiceRequest(ctx, draft, models.StatusCancelled, services.CustomerActor(callback.From.ID), "перенос существующей записи"); err != nil {
+				b.logger.Error("Ошибка отмены черновика заявки: %v", err)
+				b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+				return
+			}
+			draftCancelled = true
+		}
+	}
+
+	// Старый слот освобождается в handleTimeSelection после бронирования нового
+	session.RequestID = request.ID
+	session.QuestionID = ""
+	session.Stage = models.StageDateSelection
+	session.Data = make(map[string]interface{})
+	if err := b.dbService.SaveUserSession(ctx, session); err != nil {
+		b.logger.Error("Ошибка сохранения сессии: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	text := fmt.Sprintf("Текущая запись: %s. Выберите новую дату и время.",
+		request.AppointmentDate.Format("02.01.2006 в 15:04"))
+	if draftCancelled {
+		text = "Незаконченная новая заявка отменена.\n\n" + text
+	}
+
+	b.answerCallback(callback.ID, "")
+	b.sendMessage(chatID, text)
+	b.showAvailableDates(ctx, chatID)
+}
+
+// handleCancelRequest отменяет подтвержденную запись пользователя
+func (b *Bot) handleCancelRequest(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	request, ok := b.getOwnBookedRequest(ctx, callback, "cancelreq_")
+	if !ok {
+		return
+	}
+
+	if err := b.cancelRequest(ctx, request, services.CustomerActor(callback.From.ID), "отмена клиентом через /my"); err != nil {
+		b.logger.Error("Ошибка отмены заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	// Если отмененная заявка была текущей, сбрасываем сессию
+	if session.RequestID == request.ID {
+		session.Stage = models.StageStart
+		session.Data = make(map[string]interface{})
+		session.RequestID = primitive.NilObjectID
+		if err := b.dbService.SaveUserSession(ctx, session); err != nil {
+			b.logger.Error("Ошибка сохранения сессии: %v", err)
+		}
+	}
+
+	b.answerCallback(callback.ID, "Запись отменена")
+	b.sendMessage(chatID, fmt.Sprintf("Запись на %s отменена. Нажмите /start для создания новой заявки.",
+		request.AppointmentDate.Format("02.01.2006 в 15:04")))
+}
+
+// confirmationKeyboard возвращает кнопки подтверждения записи клиентом
+func confirmationKeyboard(requestID primitive.ObjectID) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Я приеду", fmt.Sprintf("attend_%s", requestID.Hex())),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отменить", fmt.Sprintf("decline_%s", requestID.Hex())),
+		),
+	)
+}
+
+// handleAttendConfirmation отмечает, что клиент подтвердил визит
+func (b *Bot) handleAttendConfirmation(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	request, ok := b.getOwnBookedRequest(ctx, callback, "attend_")
+	if !ok {
+		return
+	}
+
+	if request.Status == models.StatusConfirmed {
+		b.answerCallback(callback.ID, "Запись уже подтверждена")
+		return
+	}
+
+	if err := b.dbService.TransitionServiceRequest(ctx, request, models.StatusConfirmed, services.CustomerActor(callback.From.ID), "подтверждение из напоминания"); err != nil {
+		b.logger.Error("Ошибка сохранения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	b.answerCallback(callback.ID, "Спасибо, ждем вас!")
+	b.editMessage(callback.Message.Chat.ID, callback.Message.MessageID,
+		callback.Message.Text+"\n\n✅ Вы подтвердили запись. Ждем вас!")
+}
+
+// handleAttendDecline отменяет запись по отказу клиента из напоминания
+func (b *Bot) handleAttendDecline(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	request, ok := b.getOwnBookedRequest(ctx, callback, "decline_")
+	if !ok {
+		return
+	}
+
+	if err := b.cancelRequest(ctx, request, services.CustomerActor(callback.From.ID), "отказ из напоминания"); err != nil {
+		b.logger.Error("Ошибка отмены заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	if session.RequestID == request.ID {
+		session.Stage = models.StageStart
+		session.Data = make(map[string]interface{})
+		session.RequestID = primitive.NilObjectID
+		if err := b.dbService.SaveUserSession(ctx, session); err != nil {
+			b.logger.Error("Ошибка сохранения сессии: %v", err)
+		}
+	}
+
+	b.answerCallback(callback.ID, "Запись отменена")
+	b.editMessage(callback.Message.Chat.ID, callback.Message.MessageID,
+		callback.Message.Text+"\n\n❌ Запись отменена. Нажмите /start для создания новой заявки.")
+}
+
+// getOwnBookedRequest получает заявку из callback и проверяет, что она принадлежит пользователю и записана на время
+func (b *Bot) getOwnBookedRequest(ctx context.Context, callback *tgbotapi.CallbackQuery, prefix string) (*models.ServiceRequest, bool) {
+	requestID, err := primitive.ObjectIDFromHex(strings.TrimPrefix(callback.Data, prefix))
+	if err != nil {
+		b.answerCallback(callback.ID, "Неверный формат заявки")
+		return nil, false
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, requestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return nil, false
+	}
+
+	if request.UserID != callback.From.ID {
+		b.answerCallback(callback.ID, "Заявка не найдена")
+		return nil, false
+	}
+
+	if request.Status != models.StatusBooked && request.Status != models.StatusConfirmed {
+		b.answerCallback(callback.ID, "Эта запись уже неактуальна")
+		return nil, false
+	}
+
+	return request, true
+}
+
+// cancelRequest переводит заявку в статус cancelled и освобождает занятый ею слот
+func (b *Bot) cancelRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error {
+	err := b.dbService.CancelServiceRequest(ctx, request, actor, reason)
+	if err != nil && request.Status == models.StatusCancelled {
+		// Заявка отменена, не удалось только освободить слот
+		b.logger.Error("Ошибка освобождения слота: %v", err)
+		return nil
+	}
+	return err
+}
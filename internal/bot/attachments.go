@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"volvomaster/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// downloadClient клиент для скачивания вложений из Telegram
+var downloadClient = &http.Client{Timeout: 2 * time.Minute}
+
+// attachmentFromMessage извлекает из сообщения фото, видео, голосовое сообщение или документ
+func attachmentFromMessage(message *tgbotapi.Message) *models.Attachment {
+	attachment := &models.Attachment{Caption: message.Caption}
+
+	switch {
+	case len(message.Photo) > 0:
+		// Telegram присылает несколько размеров, сохраняем самый крупный
+		photo := message.Photo[len(message.Photo)-1]
+		attachment.Type = models.AttachmentPhoto
+		attachment.FileID, attachment.FileUniqueID = photo.FileID, photo.FileUniqueID
+		attachment.MimeType, attachment.Size = "image/jpeg", photo.FileSize
+	case message.Video != nil:
+		attachment.Type = models.AttachmentVideo
+		attachment.FileID, attachment.FileUniqueID = message.Video.FileID, message.Video.FileUniqueID
+		attachment.FileName, attachment.MimeType, attachment.Size = message.Video.FileName, message.Video.MimeType, int(message.Video.FileSize)
+	case message.VideoNote != nil:
+		attachment.Type = models.AttachmentVideoNote
+		attachment.FileID, attachment.FileUniqueID = message.VideoNote.FileID, message.VideoNote.FileUniqueID
+		attachment.MimeType, attachment.Size = "video/mp4", message.VideoNote.FileSize
+	case message.Voice != nil:
+		attachment.Type = models.AttachmentVoice
+		attachment.FileID, attachment.FileUniqueID = message.Voice.FileID, message.Voice.FileUniqueID
+		attachment.MimeType, attachment.Size = message.Voice.MimeType, int(message.Voice.FileSize)
+	case message.Audio != nil:
+		attachment.Type = models.AttachmentAudio
+		attachment.FileID, attachment.FileUniqueID = message.Audio.FileID, message.Audio.FileUniqueID
+		attachment.FileName, attachment.MimeType, attachment.Size = message.Audio.FileName, message.Audio.MimeType, int(message.Audio.FileSize)
+	case message.Document != nil:
+		attachment.Type = models.AttachmentDocument
+		attachment.FileID, attachment.FileUniqueID = message.Document.FileID, message.Document.FileUniqueID
+		attachment.FileName, attachment.MimeType, attachment.Size = message.Document.FileName, message.Document.MimeType, int(message.Document.FileSize)
+	default:
+		return nil
+	}
+
+	return attachment
+}
+
+// addAttachment прикладывает файл к заявке и, если настроено хранилище, сохраняет копию файла
+func (b *Bot) addAttachment(ctx context.Context, chatID int64, session *models.UserSession, attachment *models.Attachment) {
+	if b.blobs != nil {
+		// Файл в Telegram остается доступен по file ID, поэтому ошибка скачивания не критична
+		if err := b.storeAttachment(ctx, session.RequestID, attachment); err != nil {
+			b.logger.Error("Ошибка сохранения вложения в хранилище: %v", err)
+		}
+	}
+
+	if err := b.dbService.AddServiceRequestAttachment(ctx, session.RequestID, *attachment); err != nil {
+		b.logger.Error("Ошибка сохранения вложения: %v", err)
+		b.sendMessage(chatID, "Не удалось сохранить файл. Попробуйте отправить его еще раз.")
+		return
+	}
+
+	b.sendMessage(chatID, "📎 Файл добавлен к заявке. Можно отправить еще или ответить на вопрос выше.")
+}
+
+// storeAttachment скачивает файл из Telegram и кладет его в хранилище вложений
+func (b *Bot) storeAttachment(ctx context.Context, requestID primitive.ObjectID, attachment *models.Attachment) error {
+	url, err := b.messenger.FileURL(attachment.FileID)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Telegram вернул %s при скачивании файла", resp.Status)
+	}
+
+	key := fmt.Sprintf("requests/%s/%s%s", requestID.Hex(), attachment.FileUniqueID, path.Ext(url))
+	if err := b.blobs.Put(ctx, key, resp.Body, resp.ContentLength, attachment.MimeType); err != nil {
+		return err
+	}
+
+	attachment.StorageKey = key
+	return nil
+}
@@ -2,44 +2,114 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
+	"volvomaster/internal/config"
 	"volvomaster/internal/logger"
+	"volvomaster/internal/messenger"
 	"volvomaster/internal/models"
+	"volvomaster/internal/questionnaire"
 	"volvomaster/internal/services"
+	"volvomaster/internal/storage"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Bot struct {
-	api       *tgbotapi.BotAPI
-	dbService *services.DatabaseService
+	api       *tgbotapi.BotAPI // только для получения обновлений; nil, если бот создан через New
+	config    *config.Config
+	messenger messenger.Messenger
+	dbService services.Store
+	questions *questionnaire.Definition
+	blobs     storage.BlobStore
 	logger    *logger.Logger
+	updates   *dispatcher
+	webhook   *http.Server // сервер вебхука; nil при получении обновлений long polling
 	stopChan  chan struct{}
-	isRunning bool
+	stopped   chan struct{} // закрывается, когда Start перестает передавать обновления диспетчеру
+
+	// Владелец блокировок клиентов в хранилище; у каждого запущенного экземпляра бота свой
+	instanceID string
+
+	// Контекст обработчиков и фоновых задач, отменяется при остановке бота,
+	// чтобы они не обращались к базе после ее отключения
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewBot(token string, dbService *services.DatabaseService) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+func NewBot(cfg *config.Config, dbService services.Store, questions *questionnaire.Definition) (*Bot, error) {
+	api, err := tgbotapi.NewBotAPI(cfg.TelegramToken)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания бота: %w", err)
 	}
 
-	return &Bot{
-		api:       api,
+	blobs, err := storage.New(cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка настройки хранилища вложений: %w", err)
+	}
+
+	b := New(cfg, messenger.NewTelegram(api), dbService, questions, blobs)
+	b.api = api
+
+	if cfg.UpdatesMode == "webhook" {
+		if b.webhook, err = b.newWebhookServer(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// New создает бота, который отправляет сообщения через m. Сам он обновления из Telegram не получает,
+// их передают обработчику напрямую - так разговор с клиентом можно провести в тестах без сети.
+func New(cfg *config.Config, m messenger.Messenger, dbService services.Store, questions *questionnaire.Definition, blobs storage.BlobStore) *Bot {
+	b := &Bot{
+		config:    cfg,
+		messenger: m,
 		dbService: dbService,
+		questions: questions,
+		blobs:     blobs,
 		logger:    logger.New(),
 		stopChan:  make(chan struct{}),
-	}, nil
+		stopped:   make(chan struct{}),
+
+		instanceID: primitive.NewObjectID().Hex(),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	b.updates = newDispatcher(cfg.MaxConcurrentUpdates, b.processUpdate, b.logger)
+	return b
 }
 
-func (b *Bot) Start() {
-	b.isRunning = true
+// Start получает обновления до вызова Stop. Ошибка возвращается, если бот не может получать обновления:
+// например, адрес сервера вебхука занят или Telegram не принял вебхук. Тогда процесс следует завершить.
+func (b *Bot) Start() error {
+	if b.api == nil {
+		return errors.New("бот создан без подключения к Telegram и не может получать обновления")
+	}
+	defer close(b.stopped)
+
+	// Stop мог быть вызван раньше, чем запустилась горутина бота
+	select {
+	case <-b.stopChan:
+		return nil
+	default:
+	}
+
 	b.logger.Info("Бот запущен")
 
+	if b.webhook != nil {
+		return b.serveWebhook()
+	}
+	b.poll()
+	return nil
+}
+
+// poll получает обновления long polling
+func (b *Bot) poll() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
@@ -47,33 +117,77 @@ func (b *Bot) Start() {
 
 	for {
 		select {
-		case update := <-updates:
-			if !b.isRunning {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			// Обновления одного пользователя обрабатываются по очереди
+			if !b.updates.Dispatch(update) {
 				return
 			}
-			go b.handleUpdate(update)
 		case <-b.stopChan:
-			return
+			// Обновления, уже попавшие в канал, Telegram может считать доставленными,
+			// поэтому обрабатываем их, а не теряем
+			for {
+				select {
+				case update, ok := <-updates:
+					if !ok || !b.updates.Dispatch(update) {
+						return
+					}
+				default:
+					return
+				}
+			}
 		}
 	}
 }
 
-func (b *Bot) Stop() {
-	b.isRunning = false
+// Stop прекращает прием обновлений и ждет, пока будут обработаны уже принятые, но не дольше ctx.
+// Затем контекст обработчиков и фоновых задач отменяется: те, что не успели, прерывают запросы к базе.
+func (b *Bot) Stop(ctx context.Context) {
 	close(b.stopChan)
+
+	if b.api != nil {
+		if b.webhook != nil {
+			b.stopWebhook(ctx)
+		} else {
+			b.api.StopReceivingUpdates()
+		}
+
+		select {
+		case <-b.stopped:
+		case <-ctx.Done():
+		}
+	}
+
+	if err := b.updates.Shutdown(ctx); err != nil {
+		b.logger.Error("Не дождались завершения обработки обновлений: %v", err)
+	}
+	b.cancel()
 	b.logger.Info("Бот остановлен")
 }
 
-func (b *Bot) handleUpdate(update tgbotapi.Update) {
-	ctx := context.Background()
+// stopWorker останавливает фоновую задачу: закрывает stop и ждет done, но не дольше ctx.
+// Если срок истек, отменяет контекст задачи, чтобы зависший запрос к базе прервался.
+func stopWorker(ctx context.Context, stop chan struct{}, done <-chan struct{}, cancel context.CancelFunc) {
+	close(stop)
+	select {
+	case <-done:
+	case <-ctx.Done():
+		cancel()
+	}
+}
 
+func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 	// Обрабатываем callback-запросы
 	if update.CallbackQuery != nil {
-		b.handleCallbackQuery(update.CallbackQuery)
+		b.handleCallbackQuery(ctx, update.CallbackQuery)
 		return
 	}
 
-	if update.Message == nil {
+	// Заявки принимаются только в личном чате: сообщения в группе сотрудников
+	// (например, ответы на сводку записи) не должны запускать анкету
+	if update.Message == nil || update.Message.From == nil || !update.Message.Chat.IsPrivate() {
 		return
 	}
 
@@ -110,6 +224,9 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 		return
 	}
 
+	// Клиент вернулся, предложение продолжить заявку можно будет отправить снова
+	b.resetNudge(ctx, session)
+
 	// Устанавливаем chat_id в сессии, если он не установлен
 	if session.ChatID == 0 {
 		session.ChatID = chatID
@@ -134,57 +251,93 @@ func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message, sess
 	switch message.Command() {
 	case "start":
 		// Сбрасываем сессию и начинаем заново
-		session.Stage = models.StagePersonalInfo
-		session.Data = make(map[string]interface{})
-		session.RequestID = primitive.NilObjectID
-
-		if err := b.dbService.SaveUserSession(ctx, session); err != nil {
-			b.logger.Error("Ошибка сохранения сессии: %v", err)
-		}
-
 		welcomeText := `Добро пожаловать в сервисный центр Volvo! 🚗
 
 Я помогу вам записаться на обслуживание вашего автомобиля.
 
-Для начала заполним заявку. Начнем с ваших контактных данных.
-
-Как вас зовут?`
+Для начала заполним заявку. Начнем с ваших контактных данных.`
 
-		b.sendMessage(chatID, welcomeText)
+		b.startQuestionnaire(ctx, chatID, message.From.ID, session, welcomeText)
 
 	case "cancel":
-		// Отменяем текущую заявку
-		if !session.RequestID.IsZero() {
-			request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
-			if err == nil {
-				request.Status = "cancelled"
-				b.dbService.SaveServiceRequest(ctx, request)
-			}
-		}
+		b.cancelSession(ctx, chatID, message.From.ID, session, "отмена командой /cancel")
 
-		session.Stage = models.StageStart
-		session.Data = make(map[string]interface{})
-		session.RequestID = primitive.NilObjectID
-		b.dbService.SaveUserSession(ctx, session)
+	case "my":
+		b.handleMyCommand(ctx, message)
 
-		b.sendMessage(chatID, "Заявка отменена. Нажмите /start для создания новой заявки.")
+	case "admin_login":
+		b.handleAdminLoginCommand(ctx, message)
 
 	case "help":
 		helpText := `Доступные команды:
 /start - Начать новую заявку
+/my - Мои записи (перенос и отмена)
 /cancel - Отменить текущую заявку
 /help - Показать эту справку`
 		b.sendMessage(chatID, helpText)
 	}
 }
 
-func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
-	ctx := context.Background()
+// cancelSession отменяет черновик заявки клиента и сбрасывает сессию.
+// Существующая запись не отменяется: если клиент ее переносил, прекращается только перенос.
+// Отменить запись можно кнопкой в /my.
+func (b *Bot) cancelSession(ctx context.Context, chatID, userID int64, session *models.UserSession, reason string) {
+	text := "Заявка отменена. Нажмите /start для создания новой заявки."
+
+	if !session.RequestID.IsZero() {
+		request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+		switch {
+		case err != nil:
+			if !errors.Is(err, services.ErrNotFound) {
+				b.logger.Error("Ошибка получения заявки: %v", err)
+			}
+		case request.Status == models.StatusDraft:
+			if err := b.cancelRequest(ctx, request, services.CustomerActor(userID), reason); err != nil {
+				b.logger.Error("Ошибка отмены заявки: %v", err)
+			}
+		case request.Status == models.StatusBooked || request.Status == models.StatusConfirmed:
+			appointment := request.AppointmentDate.Format("02.01.2006 в 15:04")
+			if session.Stage == models.StageDateSelection {
+				text = fmt.Sprintf("Перенос записи отменен, ваша запись на %s остается в силе. Посмотреть записи можно командой /my.", appointment)
+			} else {
+				text = fmt.Sprintf("Незаконченной заявки нет. Ваша запись на %s остается в силе, отменить ее можно командой /my.", appointment)
+			}
+		}
+	}
+
+	b.resetSession(ctx, session)
+	b.sendMessage(chatID, text)
+}
+
+// resetSession возвращает сессию к началу, не трогая заявку
+func (b *Bot) resetSession(ctx context.Context, session *models.UserSession) {
+	session.Stage = models.StageStart
+	session.Data = make(map[string]interface{})
+	session.RequestID = primitive.NilObjectID
+	session.QuestionID = ""
+	if err := b.dbService.SaveUserSession(ctx, session); err != nil {
+		b.logger.Error("Ошибка сохранения сессии: %v", err)
+	}
+}
+
+func (b *Bot) handleCallbackQuery(ctx context.Context, callback *tgbotapi.CallbackQuery) {
 	userID := callback.From.ID
 	data := callback.Data
 
 	b.logger.Info("Получен callback от пользователя %d: %s", userID, data)
 
+	// Кнопки в чате сотрудников не зависят от сессии пользователя
+	if strings.HasPrefix(data, "staff_") {
+		b.handleStaffCallback(ctx, callback)
+		return
+	}
+
+	// Остальные кнопки относятся к заявке клиента и действуют только в личном чате
+	if callback.Message == nil || !callback.Message.Chat.IsPrivate() {
+		b.answerCallback(callback.ID, "")
+		return
+	}
+
 	// Получаем сессию пользователя
 	session, err := b.dbService.GetUserSession(ctx, userID)
 	if err != nil {
@@ -192,18 +345,37 @@ func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
 		return
 	}
+	b.resetNudge(ctx, session)
 
 	// Обрабатываем callback в зависимости от типа
 	if strings.HasPrefix(data, "date_") {
 		b.handleDateSelection(ctx, callback, session)
 	} else if strings.HasPrefix(data, "time_") {
 		b.handleTimeSelection(ctx, callback, session)
-	} else if strings.HasPrefix(data, "engine_") {
-		b.handleEngineTypeSelection(ctx, callback, session)
-	} else if strings.HasPrefix(data, "appeared_") {
-		b.handleProblemAppearedSelection(ctx, callback, session)
-	} else if strings.HasPrefix(data, "frequency_") {
-		b.handleProblemFrequencySelection(ctx, callback, session)
+	} else if strings.HasPrefix(data, "reschedule_") {
+		b.handleRescheduleRequest(ctx, callback, session)
+	} else if strings.HasPrefix(data, "cancelreq_") {
+		b.handleCancelRequest(ctx, callback, session)
+	} else if strings.HasPrefix(data, "attend_") {
+		b.handleAttendConfirmation(ctx, callback)
+	} else if strings.HasPrefix(data, "decline_") {
+		b.handleAttendDecline(ctx, callback, session)
+	} else if strings.HasPrefix(data, "contact_") {
+		b.handleContactOffer(ctx, callback, session)
+	} else if strings.HasPrefix(data, "garage_") {
+		b.handleGarageSelection(ctx, callback, session)
+	} else if strings.HasPrefix(data, "model_") {
+		b.handleModelSelection(ctx, callback, session)
+	} else if strings.HasPrefix(data, "back_") {
+		b.handleBackCallback(ctx, callback, session)
+	} else if strings.HasPrefix(data, "edit_") {
+		b.handleEditAnswer(ctx, callback, session)
+	} else if strings.HasPrefix(data, "review_") {
+		b.handleReviewCallback(ctx, callback, session)
+	} else if strings.HasPrefix(data, "resume_") {
+		b.handleResumeCallback(ctx, callback, session)
+	} else if strings.HasPrefix(data, "qa:") {
+		b.handleQuestionnaireCallback(ctx, callback, session)
 	} else {
 		b.answerCallback(callback.ID, "Неизвестный callback")
 	}
@@ -214,19 +386,14 @@ func (b *Bot) handleStage(ctx context.Context, message *tgbotapi.Message, sessio
 
 	switch session.Stage {
 	case models.StageStart:
-		// Начинаем с первого этапа
-		session.Stage = models.StagePersonalInfo
-		b.sendMessage(chatID, "Как вас зовут?")
-		b.dbService.SaveUserSession(ctx, session)
-
-	case models.StagePersonalInfo:
-		b.handlePersonalInfoStage(ctx, message, session)
+		// Начинаем с первого вопроса анкеты
+		b.startQuestionnaire(ctx, chatID, message.From.ID, session, "")
 
-	case models.StageCarInfo:
-		b.handleCarInfoStage(ctx, message, session)
+	case models.StagePersonalInfo, models.StageCarInfo, models.StageProblemInfo:
+		b.handleQuestionnaireMessage(ctx, message, session)
 
-	case models.StageProblemInfo:
-		b.handleProblemInfoStage(ctx, message, session)
+	case models.StageReview:
+		b.sendMessage(chatID, "Пожалуйста, проверьте заявку выше и нажмите «Подтвердить» или «Изменить».")
 
 	case models.StageDateSelection:
 		b.handleDateSelectionStage(ctx, message, session)
@@ -236,214 +403,6 @@ func (b *Bot) handleStage(ctx context.Context, message *tgbotapi.Message, sessio
 	}
 }
 
-func (b *Bot) handlePersonalInfoStage(ctx context.Context, message *tgbotapi.Message, session *models.UserSession) {
-	chatID := message.Chat.ID
-	text := message.Text
-
-	// Определяем, какое поле заполняем
-	if session.Data["name"] == nil {
-		// Сохраняем имя
-		session.Data["name"] = text
-
-		// Запрашиваем контактную информацию
-		contactText := `Укажите номер телефона или Telegram для связи:`
-		b.sendMessage(chatID, contactText)
-
-		// Сохраняем сессию после первого ответа
-		b.dbService.SaveUserSession(ctx, session)
-	} else {
-		// Сохраняем контакт
-		contact := text
-
-		// Если пользователь ввел @username, сохраняем его
-		if strings.HasPrefix(contact, "@") {
-			session.Data["contact"] = contact
-		} else {
-			// Если это номер телефона, сохраняем его
-			session.Data["contact"] = contact
-		}
-
-		// Обновляем информацию о пользователе
-		user, err := b.dbService.GetUser(ctx, message.From.ID)
-		if err == nil {
-			// Если пользователь указал номер телефона, сохраняем его
-			if !strings.HasPrefix(contact, "@") {
-				user.Phone = contact
-			}
-			// Если пользователь указал Telegram username, сохраняем его
-			if strings.HasPrefix(contact, "@") {
-				user.Telegram = contact
-			}
-			if err := b.dbService.SaveUser(ctx, user); err != nil {
-				b.logger.Error("Ошибка обновления пользователя: %v", err)
-			}
-		} else {
-			b.logger.Error("Ошибка получения пользователя для обновления: %v", err)
-		}
-
-		// Создаем заявку
-		request := &models.ServiceRequest{
-			UserID:  message.From.ID,
-			ChatID:  chatID,
-			Name:    session.Data["name"].(string),
-			Contact: session.Data["contact"].(string),
-			Stage:   models.StageCarInfo,
-			Status:  "in_progress",
-		}
-
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-
-		session.RequestID = request.ID
-		session.Stage = models.StageCarInfo
-		session.Data = make(map[string]interface{})
-
-		b.dbService.SaveUserSession(ctx, session)
-
-		// Скрываем клавиатуру
-		removeKeyboard := tgbotapi.NewRemoveKeyboard(true)
-		msg := tgbotapi.NewMessage(chatID, "")
-		msg.ReplyMarkup = removeKeyboard
-		b.api.Send(msg)
-
-		// Переходим к информации об автомобиле
-		carInfoText := `Отлично! Теперь расскажите о вашем автомобиле.
-
-Какая у вас модель Volvo?`
-		b.sendMessage(chatID, carInfoText)
-	}
-}
-
-func (b *Bot) handleCarInfoStage(ctx context.Context, message *tgbotapi.Message, session *models.UserSession) {
-	chatID := message.Chat.ID
-	text := message.Text
-
-	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
-	if err != nil {
-		b.logger.Error("Ошибка получения заявки: %v", err)
-		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	// Определяем, какое поле заполняем
-	if request.VolvoModel == "" {
-		request.VolvoModel = text
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-		b.sendMessage(chatID, "Укажите год выпуска автомобиля:")
-	} else if request.Year == "" {
-		request.Year = text
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-
-		// Показываем типы двигателей с кнопками
-		b.showEngineTypes(chatID)
-	} else if request.EngineType == "" {
-		// Обрабатываем выбор типа двигателя через callback
-		b.sendMessage(chatID, "Пожалуйста, выберите тип двигателя из предложенных вариантов выше.")
-	} else if request.EngineVolume == "" {
-		request.EngineVolume = text
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-		b.sendMessage(chatID, "Укажите пробег автомобиля на текущий момент:")
-	} else if request.Mileage == "" {
-		request.Mileage = text
-
-		// Сохраняем информацию об автомобиле
-		request.Stage = models.StageProblemInfo
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-
-		session.Stage = models.StageProblemInfo
-		b.dbService.SaveUserSession(ctx, session)
-
-		// Переходим к информации о проблеме
-		problemText := `Теперь расскажите о проблеме с автомобилем.
-
-Что именно вас беспокоит или что нужно сделать?
-(Например: "гремит спереди", "нужно заменить масло", "ошибка по двигателю", "не работает климат")`
-		b.sendMessage(chatID, problemText)
-	}
-}
-
-func (b *Bot) handleProblemInfoStage(ctx context.Context, message *tgbotapi.Message, session *models.UserSession) {
-	chatID := message.Chat.ID
-	text := message.Text
-
-	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
-	if err != nil {
-		b.logger.Error("Ошибка получения заявки: %v", err)
-		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	// Определяем, какое поле заполняем
-	if request.Problem == "" {
-		request.Problem = text
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-
-		// Показываем варианты когда появилась проблема с кнопками
-		b.showProblemAppeared(chatID)
-	} else if request.ProblemFirstAppeared == "" {
-		// Обрабатываем выбор через callback
-		b.sendMessage(chatID, "Пожалуйста, выберите вариант из предложенных выше.")
-	} else if request.ProblemFrequency == "" {
-		// Обрабатываем выбор через callback
-		b.sendMessage(chatID, "Пожалуйста, выберите вариант из предложенных выше.")
-	} else if request.SafetyImpact == "" {
-		request.SafetyImpact = text
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-		b.sendMessage(chatID, "Уже предпринимались попытки ремонта или диагностики? (Если да — что делали и где?)")
-	} else if request.PreviousRepairs == "" {
-		request.PreviousRepairs = text
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-		b.sendMessage(chatID, "Меняли ли что-то недавно? (Например: \"меняли подвеску месяц назад\")")
-	} else if request.RecentChanges == "" {
-		request.RecentChanges = text
-
-		// Сохраняем информацию о проблеме
-		request.Stage = models.StageDateSelection
-		if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-			b.logger.Error("Ошибка сохранения заявки: %v", err)
-			b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
-			return
-		}
-
-		session.Stage = models.StageDateSelection
-		b.dbService.SaveUserSession(ctx, session)
-
-		// Показываем доступные даты
-		b.showAvailableDates(chatID)
-	}
-}
-
 func (b *Bot) handleDateSelectionStage(ctx context.Context, message *tgbotapi.Message, session *models.UserSession) {
 	chatID := message.Chat.ID
 
@@ -452,9 +411,7 @@ func (b *Bot) handleDateSelectionStage(ctx context.Context, message *tgbotapi.Me
 	b.sendMessage(chatID, "Пожалуйста, выберите дату из предложенных вариантов выше.")
 }
 
-func (b *Bot) showAvailableDates(chatID int64) {
-	ctx := context.Background()
-
+func (b *Bot) showAvailableDates(ctx context.Context, chatID int64) {
 	dates, err := b.dbService.GetAvailableDates(ctx)
 	if err != nil {
 		b.logger.Error("Ошибка получения доступных дат: %v", err)
@@ -493,10 +450,7 @@ func (b *Bot) showAvailableDates(chatID int64) {
 		keyboard = append(keyboard, row)
 	}
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-
-	if _, err := b.api.Send(msg); err != nil {
+	if err := b.messenger.SendKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(keyboard...)); err != nil {
 		b.logger.Error("Ошибка отправки сообщения: %v", err)
 	}
 }
@@ -508,7 +462,7 @@ func (b *Bot) showTimeSlots(chatID int64, availableDate *models.AvailableDate) {
 	var keyboard [][]tgbotapi.InlineKeyboardButton
 	var row []tgbotapi.InlineKeyboardButton
 
-	for i, slot := range availableDate.TimeSlots {
+	for _, slot := range availableDate.TimeSlots {
 		if !slot.IsBooked {
 			button := tgbotapi.NewInlineKeyboardButtonData(
 				slot.Time,
@@ -517,106 +471,56 @@ func (b *Bot) showTimeSlots(chatID int64, availableDate *models.AvailableDate) {
 			row = append(row, button)
 
 			// Размещаем по 3 кнопки в ряд
-			if len(row) == 3 || i == len(availableDate.TimeSlots)-1 {
+			if len(row) == 3 {
 				keyboard = append(keyboard, row)
 				row = []tgbotapi.InlineKeyboardButton{}
 			}
 		}
 	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
 
 	if len(keyboard) == 0 {
 		b.sendMessage(chatID, "К сожалению, на эту дату нет свободного времени.")
 		return
 	}
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-
-	if _, err := b.api.Send(msg); err != nil {
+	if err := b.messenger.SendKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(keyboard...)); err != nil {
 		b.logger.Error("Ошибка отправки сообщения: %v", err)
 	}
 }
 
 func (b *Bot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := b.api.Send(msg); err != nil {
+	if err := b.messenger.SendText(chatID, text); err != nil {
 		b.logger.Error("Ошибка отправки сообщения: %v", err)
 	}
 }
 
-func (b *Bot) showEngineTypes(chatID int64) {
-	text := "Выберите тип двигателя:"
-	var keyboard [][]tgbotapi.InlineKeyboardButton
-
-	for _, engineType := range models.EngineTypes {
-		key := models.EngineTypeKeys[engineType]
-		row := []tgbotapi.InlineKeyboardButton{
-			tgbotapi.NewInlineKeyboardButtonData(
-				engineType,
-				fmt.Sprintf("engine_%s", key),
-			),
-		}
-		keyboard = append(keyboard, row)
-	}
-
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-
-	if _, err := b.api.Send(msg); err != nil {
-		b.logger.Error("Ошибка отправки сообщения: %v", err)
-	}
-}
-
-func (b *Bot) showProblemAppeared(chatID int64) {
-	text := "Когда впервые появилась проблема?"
-	var keyboard [][]tgbotapi.InlineKeyboardButton
-
-	for _, option := range models.ProblemAppeared {
-		key := models.ProblemAppearedKeys[option]
-		row := []tgbotapi.InlineKeyboardButton{
-			tgbotapi.NewInlineKeyboardButtonData(
-				option,
-				fmt.Sprintf("appeared_%s", key),
-			),
-		}
-		keyboard = append(keyboard, row)
-	}
-
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-
-	if _, err := b.api.Send(msg); err != nil {
-		b.logger.Error("Ошибка отправки сообщения: %v", err)
+func (b *Bot) editMessage(chatID int64, messageID int, text string) {
+	if err := b.messenger.EditMessage(chatID, messageID, text, nil); err != nil {
+		b.logger.Error("Ошибка редактирования сообщения: %v", err)
 	}
 }
 
-func (b *Bot) showProblemFrequency(chatID int64) {
-	text := "Проблема проявляется постоянно или периодически?"
-	var keyboard [][]tgbotapi.InlineKeyboardButton
+// staleTimeSelectionText ответ на кнопку даты или времени, когда клиент уже не выбирает время
+const staleTimeSelectionText = "Выбор времени уже неактуален. Чтобы перенести запись, используйте /my"
 
-	for _, option := range models.ProblemFrequencies {
-		key := models.ProblemFrequencyKeys[option]
-		row := []tgbotapi.InlineKeyboardButton{
-			tgbotapi.NewInlineKeyboardButtonData(
-				option,
-				fmt.Sprintf("frequency_%s", key),
-			),
-		}
-		keyboard = append(keyboard, row)
-	}
-
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-
-	if _, err := b.api.Send(msg); err != nil {
-		b.logger.Error("Ошибка отправки сообщения: %v", err)
-	}
+// selectingDate проверяет, что клиент сейчас выбирает дату и время для заявки из сессии.
+// Кнопки из старых сообщений после записи не действуют, пока клиент сам не начнет перенос.
+func selectingDate(session *models.UserSession) bool {
+	return session.Stage == models.StageDateSelection && !session.RequestID.IsZero()
 }
 
 func (b *Bot) handleDateSelection(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
 	chatID := callback.Message.Chat.ID
 	data := callback.Data
 
+	if !selectingDate(session) {
+		b.answerCallback(callback.ID, staleTimeSelectionText)
+		return
+	}
+
 	dateID := strings.TrimPrefix(data, "date_")
 	if objectID, err := primitive.ObjectIDFromHex(dateID); err == nil {
 		// Получаем выбранную дату
@@ -639,6 +543,14 @@ func (b *Bot) handleTimeSelection(ctx context.Context, callback *tgbotapi.Callba
 	chatID := callback.Message.Chat.ID
 	data := callback.Data
 
+	// Кнопки времени из старых сообщений не действуют, в том числе после сброса сессии.
+	// На этапе проверки заявки ниже клиенту предлагается сначала ее подтвердить.
+	reviewing := session.Stage == models.StageReview && !session.RequestID.IsZero()
+	if !selectingDate(session) && !reviewing {
+		b.answerCallback(callback.ID, staleTimeSelectionText)
+		return
+	}
+
 	timeSlot := strings.TrimPrefix(data, "time_")
 	parts := strings.Split(timeSlot, "_")
 	if len(parts) == 2 {
@@ -662,6 +574,45 @@ func (b *Bot) handleTimeSelection(ctx context.Context, callback *tgbotapi.Callba
 				return
 			}
 
+			if !services.CanTransition(request.Status, models.StatusBooked) {
+				b.answerCallback(callback.ID, "Эта заявка уже неактуальна. Нажмите /start для создания новой.")
+				return
+			}
+			// Записываем только заявку, которую клиент проверил и подтвердил
+			if request.Status == models.StatusDraft && request.ApprovedAt == nil {
+				b.answerCallback(callback.ID, "Сначала подтвердите заявку")
+				b.showReview(ctx, chatID, session, request)
+				return
+			}
+			// Подтвержденная заявка на этапе проверки - тоже старая кнопка
+			if !selectingDate(session) {
+				b.answerCallback(callback.ID, staleTimeSelectionText)
+				return
+			}
+			if request.AvailableDateID == objectID && request.TimeSlot == timeStr {
+				b.answerCallback(callback.ID, "Вы уже записаны на это время")
+				return
+			}
+
+			// Атомарно бронируем слот за заявкой
+			if err := b.dbService.ReserveTimeSlot(ctx, objectID, timeStr, request.ID); err != nil {
+				if errors.Is(err, services.ErrSlotUnavailable) {
+					b.answerCallback(callback.ID, "Это время уже занято")
+					b.sendMessage(chatID, "К сожалению, это время только что заняли. Пожалуйста, выберите другое время.")
+
+					// Показываем актуальные слоты на выбранную дату
+					if refreshedDate, err := b.dbService.GetAvailableDateByID(ctx, objectID); err == nil {
+						b.showTimeSlots(chatID, refreshedDate)
+					} else {
+						b.showAvailableDates(ctx, chatID)
+					}
+					return
+				}
+				b.logger.Error("Ошибка бронирования слота: %v", err)
+				b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+				return
+			}
+
 			// Создаем полную дату и время
 			appointmentTime := time.Date(
 				availableDate.Date.Year(),
@@ -675,21 +626,53 @@ func (b *Bot) handleTimeSelection(ctx context.Context, callback *tgbotapi.Callba
 				appointmentTime = appointmentTime.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
 			}
 
+			previousDateID, previousSlot := request.AvailableDateID, request.TimeSlot
+
 			request.AppointmentDate = appointmentTime
+			request.AvailableDateID = objectID
+			request.TimeSlot = timeStr
 			request.Stage = models.StageCompleted
-			request.Status = "completed"
 
-			if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
+			reason := ""
+			if !previousDateID.IsZero() {
+				reason = "перенос записи"
+			}
+
+			if err := b.dbService.BookServiceRequest(ctx, request, services.CustomerActor(callback.From.ID), reason); err != nil {
+				// Освобождаем слот, чтобы он не остался занятым без заявки
+				if err := b.dbService.ReleaseTimeSlot(ctx, objectID, timeStr, request.ID); err != nil {
+					b.logger.Error("Ошибка освобождения слота: %v", err)
+				}
+				// Пока клиент выбирал время, заявку отменили или изменили в админ-панели
+				if errors.Is(err, services.ErrInvalidTransition) {
+					b.answerCallback(callback.ID, "Эта заявка уже неактуальна. Нажмите /start для создания новой.")
+					return
+				}
 				b.logger.Error("Ошибка сохранения заявки: %v", err)
 				b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
 				return
 			}
 
+			// Освобождаем ранее занятый заявкой слот, если время изменилось
+			if !previousDateID.IsZero() && (previousDateID != objectID || previousSlot != timeStr) {
+				if err := b.dbService.ReleaseTimeSlot(ctx, previousDateID, previousSlot, request.ID); err != nil {
+					b.logger.Error("Ошибка освобождения слота: %v", err)
+				}
+			}
+
 			session.Stage = models.StageCompleted
 			b.dbService.SaveUserSession(ctx, session)
 
+			// Убираем кнопки выбора времени, чтобы по ним нельзя было нажать позже
+			b.editMessage(chatID, callback.Message.MessageID,
+				fmt.Sprintf("Выбрано время: %s", appointmentTime.Format("02.01.2006 в 15:04")))
+
 			// Отправляем подтверждение
-			confirmationText := fmt.Sprintf(`✅ Заявка успешно создана!
+			header := "✅ Заявка успешно создана!"
+			if !previousDateID.IsZero() {
+				header = "✅ Запись перенесена!"
+			}
+			confirmationText := fmt.Sprintf(`%s
 
 📋 Информация о заявке:
 👤 Имя: %s
@@ -698,12 +681,14 @@ func (b *Bot) handleTimeSelection(ctx context.Context, callback *tgbotapi.Callba
 🔧 Проблема: %s
 📅 Дата записи: %s
 
-Мы свяжемся с вами для подтверждения записи.`,
-				request.Name, request.Contact, request.VolvoModel, request.Year,
+Накануне визита мы пришлем напоминание, в котором можно подтвердить или отменить запись.`,
+				header, request.Name, request.Contact, request.VolvoModel, request.Year,
 				request.Problem, appointmentTime.Format("02.01.2006 в 15:04"))
 
 			b.sendMessage(chatID, confirmationText)
 			b.answerCallback(callback.ID, "Заявка создана успешно!")
+
+			b.notifyStaff(request, !previousDateID.IsZero())
 		} else {
 			b.answerCallback(callback.ID, "Неверный формат даты")
 		}
@@ -712,84 +697,8 @@ func (b *Bot) handleTimeSelection(ctx context.Context, callback *tgbotapi.Callba
 	}
 }
 
-func (b *Bot) handleEngineTypeSelection(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
-	chatID := callback.Message.Chat.ID
-	data := callback.Data
-
-	engineKey := strings.TrimPrefix(data, "engine_")
-	engineType := models.EngineTypeValues[engineKey]
-
-	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
-	if err != nil {
-		b.logger.Error("Ошибка получения заявки: %v", err)
-		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	request.EngineType = engineType
-	if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-		b.logger.Error("Ошибка сохранения заявки: %v", err)
-		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	b.sendMessage(chatID, "Укажите объем двигателя (если знаете):")
-	b.answerCallback(callback.ID, "")
-}
-
-func (b *Bot) handleProblemAppearedSelection(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
-	chatID := callback.Message.Chat.ID
-	data := callback.Data
-
-	appearedKey := strings.TrimPrefix(data, "appeared_")
-	appeared := models.ProblemAppearedValues[appearedKey]
-
-	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
-	if err != nil {
-		b.logger.Error("Ошибка получения заявки: %v", err)
-		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	request.ProblemFirstAppeared = appeared
-	if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-		b.logger.Error("Ошибка сохранения заявки: %v", err)
-		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	b.showProblemFrequency(chatID)
-	b.answerCallback(callback.ID, "")
-}
-
-func (b *Bot) handleProblemFrequencySelection(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
-	chatID := callback.Message.Chat.ID
-	data := callback.Data
-
-	frequencyKey := strings.TrimPrefix(data, "frequency_")
-	frequency := models.ProblemFrequencyValues[frequencyKey]
-
-	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
-	if err != nil {
-		b.logger.Error("Ошибка получения заявки: %v", err)
-		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	request.ProblemFrequency = frequency
-	if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
-		b.logger.Error("Ошибка сохранения заявки: %v", err)
-		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
-		return
-	}
-
-	b.sendMessage(chatID, "Влияет ли это на движение или безопасность? (Например: \"машина не заводится\", \"перестали работать тормоза\")")
-	b.answerCallback(callback.ID, "")
-}
-
 func (b *Bot) answerCallback(callbackID string, text string) {
-	callback := tgbotapi.NewCallback(callbackID, text)
-	if _, err := b.api.Request(callback); err != nil {
+	if err := b.messenger.AnswerCallback(callbackID, text); err != nil {
 		b.logger.Error("Ошибка ответа на callback: %v", err)
 	}
 }
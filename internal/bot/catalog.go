@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/questionnaire"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// otherModelKey отметка в данных сессии: клиент выбрал модель не из каталога
+const otherModelKey = "other_model"
+
+// modelKeyboard возвращает кнопки выбора модели из каталога с вариантом «Другая модель»
+func (b *Bot) modelKeyboard(ctx context.Context) [][]tgbotapi.InlineKeyboardButton {
+	catalogModels, err := b.dbService.GetVolvoModels(ctx)
+	if err != nil {
+		b.logger.Error("Ошибка получения каталога моделей: %v", err)
+		return nil
+	}
+	if len(catalogModels) == 0 {
+		return nil
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, model := range catalogModels {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(model.Name, "model_"+model.ID))
+
+		// Размещаем по 4 кнопки в ряд
+		if len(row) == 4 {
+			keyboard = append(keyboard, row)
+			row = []tgbotapi.InlineKeyboardButton{}
+		}
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+	keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Другая модель", "model_other"),
+	))
+
+	return keyboard
+}
+
+// handleModelSelection обрабатывает выбор модели из каталога
+func (b *Bot) handleModelSelection(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	q := b.questions.Question(session.QuestionID)
+	if q == nil || q.Validation == nil || q.Validation.Format != questionnaire.FormatVolvoModel {
+		b.answerCallback(callback.ID, "Этот вопрос уже неактуален")
+		return
+	}
+
+	modelID := strings.TrimPrefix(callback.Data, "model_")
+	if modelID == "other" {
+		session.Data[otherModelKey] = true
+		b.dbService.SaveUserSession(ctx, session)
+
+		b.answerCallback(callback.ID, "")
+		b.sendMessage(chatID, "Напишите, пожалуйста, модель вашего Volvo:")
+		return
+	}
+
+	model, err := b.dbService.GetVolvoModel(ctx, modelID)
+	if err != nil {
+		b.logger.Error("Ошибка получения модели %s: %v", modelID, err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	b.answerCallback(callback.ID, "")
+	b.applyAnswer(ctx, chatID, callback.From.ID, session, q, nil, model.Name)
+}
+
+// formatProductionYears возвращает годы выпуска поколений модели, например "2002–2014, с 2014"
+func formatProductionYears(model *models.VolvoModel) string {
+	var periods []string
+	for _, g := range model.Generations {
+		if g.YearTo == 0 {
+			periods = append(periods, fmt.Sprintf("с %d", g.YearFrom))
+		} else {
+			periods = append(periods, fmt.Sprintf("%d–%d", g.YearFrom, g.YearTo))
+		}
+	}
+	return strings.Join(periods, ", ")
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/validation"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// personalSection раздел анкеты с контактными данными
+const personalSection = "personal"
+
+// contactOfferKey отметка в данных сессии: клиенту предложено продолжить с сохраненными контактами
+const contactOfferKey = "contact_offer"
+
+// offerKnownContact предлагает постоянному клиенту продолжить с сохраненными именем и контактом.
+// Возвращает false, если сохраненных данных нет.
+func (b *Bot) offerKnownContact(ctx context.Context, chatID, userID int64, session *models.UserSession, intro string) bool {
+	user, err := b.dbService.GetUser(ctx, userID)
+	if err != nil || user.DisplayName() == "" || user.Contact() == "" {
+		return false
+	}
+
+	session.Data[contactOfferKey] = true
+	b.dbService.SaveUserSession(ctx, session)
+
+	text := fmt.Sprintf("Продолжить как %s, %s?", user.DisplayName(), user.Contact())
+	if intro != "" {
+		text = intro + "\n\n" + text
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✅ Да, это я", "contact_yes")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✏️ Ввести другие данные", "contact_no")),
+	)
+
+	if err := b.messenger.SendKeyboard(chatID, text, keyboard); err != nil {
+		b.logger.Error("Ошибка отправки сообщения: %v", err)
+	}
+	return true
+}
+
+// handleContactOffer обрабатывает ответ на предложение продолжить с сохраненными контактами
+func (b *Bot) handleContactOffer(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	offered, _ := session.Data[contactOfferKey].(bool)
+	q := b.questions.Question(session.QuestionID)
+	if !offered || q == nil {
+		b.answerCallback(callback.ID, "Этот вопрос уже неактуален")
+		return
+	}
+
+	delete(session.Data, contactOfferKey)
+
+	user, err := b.dbService.GetUser(ctx, callback.From.ID)
+	if callback.Data == "contact_no" || err != nil {
+		b.dbService.SaveUserSession(ctx, session)
+
+		b.answerCallback(callback.ID, "")
+		b.askQuestion(ctx, chatID, q, "")
+		return
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	b.prefillField(request, "name", user.DisplayName())
+	b.prefillField(request, "contact", user.Contact())
+	if user.Phone != "" {
+		// Номер из отправленного контакта Telegram может быть без "+"
+		if phone, err := validation.NormalizePhone("+" + strings.TrimPrefix(user.Phone, "+")); err == nil {
+			request.Phone = phone
+		}
+	}
+
+	b.answerCallback(callback.ID, "")
+	b.moveToQuestion(ctx, chatID, callback.From.ID, session, request, q, b.skipAnswered(request, q), "")
+}
+
+// contactKeyboard возвращает клавиатуру с кнопкой отправки своего номера телефона
+func contactKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	keyboard := tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(
+		tgbotapi.NewKeyboardButtonContact("📱 Отправить мой номер"),
+	))
+	keyboard.OneTimeKeyboard = true
+	keyboard.ResizeKeyboard = true
+	return keyboard
+}
+
+// removeReplyKeyboard убирает клавиатуру с кнопкой отправки номера
+func (b *Bot) removeReplyKeyboard(chatID int64, text string) {
+	if err := b.messenger.SendKeyboard(chatID, text, tgbotapi.NewRemoveKeyboard(true)); err != nil {
+		b.logger.Error("Ошибка отправки сообщения: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"sync"
+
+	"volvomaster/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultMaxConcurrentUpdates сколько обновлений обрабатывается одновременно, если в конфигурации не задано
+const defaultMaxConcurrentUpdates = 16
+
+// dispatcher обрабатывает обновления одного пользователя строго по очереди,
+// а обновления разных пользователей - параллельно, но не больше limit одновременно.
+// Так два быстрых сообщения клиента не перезаписывают сессию и заявку друг друга.
+type dispatcher struct {
+	handle func(tgbotapi.Update)
+	logger *logger.Logger
+	slots  chan struct{}
+
+	mu      sync.Mutex
+	queues  map[int64][]tgbotapi.Update // очереди пользователей, для которых запущен обработчик
+	closed  bool
+	dropped bool // срок остановки истек: необработанные обновления из очередей отбрасываются
+	wg      sync.WaitGroup
+}
+
+func newDispatcher(limit int, handle func(tgbotapi.Update), logger *logger.Logger) *dispatcher {
+	if limit <= 0 {
+		limit = defaultMaxConcurrentUpdates
+	}
+	return &dispatcher{
+		handle: handle,
+		logger: logger,
+		slots:  make(chan struct{}, limit),
+		queues: make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// Dispatch ставит обновление в очередь его пользователя.
+// Возвращает false, если диспетчер уже остановлен.
+func (d *dispatcher) Dispatch(update tgbotapi.Update) bool {
+	key := updateKey(update)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return false
+	}
+
+	queue, running := d.queues[key]
+	d.queues[key] = append(queue, update)
+	if !running {
+		d.wg.Add(1)
+		go d.run(key)
+	}
+	return true
+}
+
+// Shutdown перестает принимать новые обновления и ждет, пока будут обработаны уже принятые.
+// Если ctx завершится раньше, еще не начатые обновления отбрасываются и возвращается ошибка ctx;
+// уже начатые обработчики продолжают работу, пока вызывающий не отменит их контекст.
+func (d *dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	d.mu.Lock()
+	d.dropped = true
+	pending := 0
+	for _, queue := range d.queues {
+		pending += len(queue)
+	}
+	d.mu.Unlock()
+
+	if pending > 0 {
+		d.logger.Error("Остановка: не обработано обновлений из очередей: %d", pending)
+	}
+	return ctx.Err()
+}
+
+// run обрабатывает очередь пользователя, пока она не опустеет
+func (d *dispatcher) run(key int64) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 || d.dropped {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[key] = queue[1:]
+		d.mu.Unlock()
+
+		d.slots <- struct{}{}
+		d.process(update)
+		<-d.slots
+	}
+}
+
+func (d *dispatcher) process(update tgbotapi.Update) {
+	// Ошибка в обработке одного обновления не должна останавливать очередь пользователя
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Error("Паника при обработке обновления %d: %v", update.UpdateID, r)
+		}
+	}()
+
+	d.handle(update)
+}
+
+// updateKey возвращает ID пользователя, от которого пришло обновление:
+// сессии хранятся по пользователю, поэтому и очередь у каждого своя
+func updateKey(update tgbotapi.Update) int64 {
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"volvomaster/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDispatcherPerUserOrder(t *testing.T) {
+	const users, perUser = 2, 5
+
+	var (
+		mu     sync.Mutex
+		active = make(map[int64]int)   // обработчики пользователя, выполняющиеся сейчас
+		order  = make(map[int64][]int) // ID обновлений пользователя в порядке обработки
+	)
+	started := make(chan int64, users*perUser)
+	release := make(chan struct{})
+
+	d := newDispatcher(users, func(update tgbotapi.Update) {
+		user := update.Message.From.ID
+
+		mu.Lock()
+		active[user]++
+		if active[user] > 1 {
+			t.Errorf("обновления пользователя %d обрабатываются одновременно", user)
+		}
+		order[user] = append(order[user], update.UpdateID)
+		mu.Unlock()
+
+		started <- user
+		<-release
+
+		mu.Lock()
+		active[user]--
+		mu.Unlock()
+	}, logger.New())
+
+	// Обновления пользователей чередуются
+	for i := 1; i <= users*perUser; i++ {
+		user := int64(i%users + 1)
+		d.Dispatch(tgbotapi.Update{UpdateID: i, Message: &tgbotapi.Message{From: &tgbotapi.User{ID: user}}})
+	}
+
+	// Первые обновления обоих пользователей выполняются одновременно
+	seen := make(map[int64]bool)
+	for len(seen) < users {
+		select {
+		case user := <-started:
+			if seen[user] {
+				t.Fatalf("второе обновление пользователя %d началось раньше обновления другого пользователя", user)
+			}
+			seen[user] = true
+		case <-time.After(time.Second):
+			t.Fatal("обновления разных пользователей не обрабатываются параллельно")
+		}
+	}
+	close(release)
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	for user := int64(1); user <= users; user++ {
+		ids := order[user]
+		if len(ids) != perUser {
+			t.Errorf("у пользователя %d обработано %d обновлений, ожидалось %d", user, len(ids), perUser)
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Errorf("обновления пользователя %d обработаны не по порядку: %v", user, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestDispatcherShutdownDeadline(t *testing.T) {
+	release := make(chan struct{})
+	var handled atomic.Int32
+
+	d := newDispatcher(1, func(tgbotapi.Update) {
+		handled.Add(1)
+		<-release
+	}, logger.New())
+
+	update := tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: testUserID}}}
+	for i := 0; i < 3; i++ {
+		if !d.Dispatch(update) {
+			t.Fatal("диспетчер не принял обновление до остановки")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown вернул %v, ожидался выход по сроку", err)
+	}
+	if d.Dispatch(update) {
+		t.Error("диспетчер принял обновление после остановки")
+	}
+
+	// Начатый обработчик завершается, оставшиеся в очереди обновления отбрасываются
+	close(release)
+	d.wg.Wait()
+	if n := handled.Load(); n != 1 {
+		t.Errorf("обработано %d обновлений, ожидалось 1", n)
+	}
+}
+
+func TestDispatcherShutdownWaits(t *testing.T) {
+	var handled atomic.Int32
+	d := newDispatcher(2, func(tgbotapi.Update) {
+		time.Sleep(10 * time.Millisecond)
+		handled.Add(1)
+	}, logger.New())
+
+	for id := int64(1); id <= 4; id++ {
+		d.Dispatch(tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: id % 2}}})
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if n := handled.Load(); n != 4 {
+		t.Errorf("обработано %d обновлений, ожидалось 4", n)
+	}
+}
+
+func TestStopWorkerDeadline(t *testing.T) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	workerCtx, cancelWorker := context.WithCancel(context.Background())
+	defer cancelWorker()
+
+	// Задача застряла в запросе к базе и завершится только после отмены своего контекста
+	go func() {
+		defer close(done)
+		<-stop
+		<-workerCtx.Done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stopWorker(ctx, stop, done, cancelWorker)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("контекст задачи не отменен по истечении срока остановки")
+	}
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"volvomaster/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// carSection раздел анкеты с вопросами об автомобиле
+const carSection = "car"
+
+// garageOfferKey отметка в данных сессии: клиенту предложен выбор автомобиля из гаража
+const garageOfferKey = "garage_offer"
+
+// maxGarageVehicles сколько последних автомобилей показывать в гараже
+const maxGarageVehicles = 5
+
+// offerGarage предлагает постоянному клиенту выбрать автомобиль из гаража.
+// Возвращает false, если сохраненных автомобилей нет.
+func (b *Bot) offerGarage(ctx context.Context, chatID, userID int64, session *models.UserSession, intro string) bool {
+	vehicles, err := b.dbService.GetUserVehicles(ctx, userID)
+	if err != nil {
+		b.logger.Error("Ошибка получения автомобилей пользователя: %v", err)
+		return false
+	}
+	if len(vehicles) == 0 {
+		return false
+	}
+	if len(vehicles) > maxGarageVehicles {
+		vehicles = vehicles[:maxGarageVehicles]
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, vehicle := range vehicles {
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚗 Мой "+vehicle.Title(), "garage_"+vehicle.ID.Hex()),
+		))
+	}
+	keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Другой автомобиль", "garage_new"),
+	))
+
+	session.Data[garageOfferKey] = true
+	b.dbService.SaveUserSession(ctx, session)
+
+	text := "Выберите автомобиль из вашего гаража или добавьте новый:"
+	if intro != "" {
+		text = intro + "\n\n" + text
+	}
+
+	if err := b.messenger.SendKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(keyboard...)); err != nil {
+		b.logger.Error("Ошибка отправки сообщения: %v", err)
+	}
+	return true
+}
+
+// handleGarageSelection обрабатывает выбор автомобиля из гаража:
+// данные автомобиля подставляются в заявку, спрашивается только текущий пробег
+func (b *Bot) handleGarageSelection(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	offered, _ := session.Data[garageOfferKey].(bool)
+	q := b.questions.Question(session.QuestionID)
+	if !offered || q == nil {
+		b.answerCallback(callback.ID, "Этот вопрос уже неактуален")
+		return
+	}
+
+	vehicleID := strings.TrimPrefix(callback.Data, "garage_")
+	if vehicleID == "new" {
+		delete(session.Data, garageOfferKey)
+		b.dbService.SaveUserSession(ctx, session)
+
+		b.answerCallback(callback.ID, "")
+		b.askQuestion(ctx, chatID, q, "")
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(vehicleID)
+	if err != nil {
+		b.answerCallback(callback.ID, "Неверный формат автомобиля")
+		return
+	}
+
+	vehicle, err := b.dbService.GetVehicle(ctx, objectID)
+	if err != nil || vehicle.UserID != callback.From.ID {
+		b.answerCallback(callback.ID, "Автомобиль не найден")
+		return
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	request.VehicleID = vehicle.ID
+	request.ModelID = vehicle.ModelID
+	request.YearNum = vehicle.YearNum
+	request.EngineVolumeL = vehicle.EngineVolumeL
+	b.prefillField(request, "vin", vehicle.VIN)
+	b.prefillField(request, "volvo_model", vehicle.VolvoModel)
+	b.prefillField(request, "year", vehicle.Year)
+	b.prefillField(request, "engine_type", vehicle.EngineType)
+	b.prefillField(request, "engine_volume", vehicle.EngineVolume)
+
+	delete(session.Data, garageOfferKey)
+	b.answerCallback(callback.ID, "")
+
+	next := b.skipAnswered(request, q)
+	intro := ""
+	if next != nil && next.Field == "mileage" && vehicle.Mileage != "" {
+		intro = "В прошлый раз вы указывали пробег: " + vehicle.Mileage + "."
+	}
+	b.moveToQuestion(ctx, chatID, callback.From.ID, session, request, q, next, intro)
+}
+
+// rememberVehicle сохраняет автомобиль из заявки в гараж клиента
+func (b *Bot) rememberVehicle(ctx context.Context, request *models.ServiceRequest) {
+	if request.VolvoModel == "" {
+		return
+	}
+
+	// Автомобиль, выбранный из гаража, обновляем, только если клиент не поменял в анкете
+	// модель, год или VIN: иначе это другой автомобиль, и прежний затирать нельзя
+	var vehicle *models.Vehicle
+	if !request.VehicleID.IsZero() {
+		if existing, err := b.dbService.GetVehicle(ctx, request.VehicleID); err == nil && existing.UserID == request.UserID && sameVehicle(existing, request) {
+			vehicle = existing
+		}
+	}
+
+	if vehicle == nil {
+		vehicles, err := b.dbService.GetUserVehicles(ctx, request.UserID)
+		if err != nil {
+			b.logger.Error("Ошибка получения автомобилей пользователя: %v", err)
+		}
+		for _, existing := range vehicles {
+			if sameVehicle(existing, request) {
+				vehicle = existing
+				break
+			}
+		}
+	}
+
+	if vehicle == nil {
+		vehicle = &models.Vehicle{UserID: request.UserID}
+	}
+
+	if request.VIN != "" {
+		vehicle.VIN = request.VIN
+	}
+	vehicle.ModelID = request.ModelID
+	vehicle.VolvoModel = request.VolvoModel
+	vehicle.Year = request.Year
+	vehicle.YearNum = request.YearNum
+	vehicle.EngineType = request.EngineType
+	vehicle.EngineVolume = request.EngineVolume
+	vehicle.EngineVolumeL = request.EngineVolumeL
+	vehicle.Mileage = request.Mileage
+	vehicle.MileageKm = request.MileageKm
+
+	if err := b.dbService.SaveVehicle(ctx, vehicle); err != nil {
+		b.logger.Error("Ошибка сохранения автомобиля: %v", err)
+		return
+	}
+	request.VehicleID = vehicle.ID
+}
+
+// sameVehicle проверяет, относится ли заявка к уже сохраненному автомобилю
+func sameVehicle(vehicle *models.Vehicle, request *models.ServiceRequest) bool {
+	if vehicle.VIN != "" && request.VIN != "" {
+		return vehicle.VIN == request.VIN
+	}
+	return strings.EqualFold(vehicle.VolvoModel, request.VolvoModel) && vehicle.Year == request.Year
+}
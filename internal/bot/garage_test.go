@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"volvomaster/internal/models"
+)
+
+func TestRememberVehicleChangedModel(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	saved := &models.Vehicle{UserID: testUserID, ModelID: "xc60", VolvoModel: "XC60", Year: "2018", Mileage: "85000"}
+	if err := env.store.SaveVehicle(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// Клиент выбрал автомобиль из гаража, но в анкете исправил модель и год
+	request := &models.ServiceRequest{UserID: testUserID, VehicleID: saved.ID, ModelID: "xc90", VolvoModel: "XC90", Year: "2021", Mileage: "30000"}
+	env.bot.rememberVehicle(ctx, request)
+
+	if request.VehicleID == saved.ID {
+		t.Error("заявка по-прежнему ссылается на прежний автомобиль")
+	}
+	previous, err := env.store.GetVehicle(ctx, saved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.VolvoModel != "XC60" || previous.Year != "2018" || previous.Mileage != "85000" {
+		t.Errorf("прежний автомобиль перезаписан: %+v", previous)
+	}
+	vehicles, _ := env.store.GetUserVehicles(ctx, testUserID)
+	if len(vehicles) != 2 {
+		t.Errorf("в гараже %d автомобилей, ожидалось 2", len(vehicles))
+	}
+
+	// Тот же автомобиль с новым пробегом обновляется на месте
+	request = &models.ServiceRequest{UserID: testUserID, VehicleID: saved.ID, ModelID: "xc60", VolvoModel: "XC60", Year: "2018", Mileage: "90000"}
+	env.bot.rememberVehicle(ctx, request)
+	if request.VehicleID != saved.ID {
+		t.Error("для того же автомобиля создан новый")
+	}
+	if updated, _ := env.store.GetVehicle(ctx, saved.ID); updated.Mileage != "90000" {
+		t.Errorf("пробег не обновлен: %q", updated.Mileage)
+	}
+}
@@ -0,0 +1,275 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"volvomaster/internal/catalog"
+	"volvomaster/internal/config"
+	"volvomaster/internal/messenger"
+	"volvomaster/internal/models"
+	"volvomaster/internal/questionnaire"
+	"volvomaster/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// staffChatID чат сотрудников в тестовой конфигурации
+const staffChatID = -100
+
+// testEnv бот с хранилищем в памяти и поддельным мессенджером
+type testEnv struct {
+	t     *testing.T
+	bot   *Bot
+	fake  *messenger.Fake
+	store *services.MemoryStore
+
+	updateID   int
+	callbackID int
+	history    []messenger.Message // все сообщения бота, включая изменения
+	texts      map[int]string      // тексты отправленных сообщений по ID, для callback.Message.Text
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	store := services.NewMemoryStore()
+	if err := store.SeedVolvoModels(context.Background(), catalog.DefaultModels); err != nil {
+		t.Fatalf("заполнение каталога: %v", err)
+	}
+
+	cfg := &config.Config{
+		StaffChatID:          staffChatID,
+		MaxConcurrentUpdates: 1,
+	}
+	fake := messenger.NewFake()
+
+	return &testEnv{
+		t:     t,
+		bot:   New(cfg, fake, store, questionnaire.Default(), nil),
+		fake:  fake,
+		store: store,
+		texts: make(map[int]string),
+	}
+}
+
+// addDate добавляет рабочий день через days дней со свободными слотами
+func (e *testEnv) addDate(days int, slots ...string) *models.AvailableDate {
+	e.t.Helper()
+
+	date := &models.AvailableDate{
+		Date:     time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, days),
+		IsActive: true,
+	}
+	for _, slot := range slots {
+		date.TimeSlots = append(date.TimeSlots, models.TimeSlot{Time: slot})
+	}
+
+	if err := e.store.SaveAvailableDate(context.Background(), date); err != nil {
+		e.t.Fatalf("сохранение даты: %v", err)
+	}
+	return date
+}
+
+// dateButton текст кнопки выбора даты
+func dateButton(date *models.AvailableDate) string {
+	return date.Date.Format("02.01.2006") + " (" + getWeekdayName(date.Date.Weekday()) + ")"
+}
+
+// client клиент бота, от имени которого отправляются обновления
+type client struct {
+	env     *testEnv
+	userID  int64
+	replies []messenger.Message // ответы бота этому клиенту на последний шаг
+	answer  *messenger.CallbackAnswer
+}
+
+func (e *testEnv) client(userID int64) *client {
+	return &client{env: e, userID: userID}
+}
+
+// step один шаг разговора: клиент что-то отправляет, бот должен ответить
+type step struct {
+	send    string // текст сообщения; начинается с "/" - команда
+	contact string // номер телефона, отправленный кнопкой «Отправить мой номер»
+	press   string // текст inline-кнопки в последнем сообщении, где она есть
+	data    string // произвольный callback, как от устаревшей или подделанной кнопки
+
+	want       string // фрагмент текста, который должен быть в одном из ответов
+	wantAnswer string // текст всплывающего ответа на нажатие кнопки
+}
+
+func (s step) String() string {
+	switch {
+	case s.send != "":
+		return "сообщение " + strconv.Quote(s.send)
+	case s.contact != "":
+		return "контакт " + s.contact
+	case s.press != "":
+		return "кнопка " + strconv.Quote(s.press)
+	default:
+		return "callback " + strconv.Quote(s.data)
+	}
+}
+
+// run выполняет шаги по порядку и проверяет ответы бота
+func (c *client) run(steps ...step) {
+	c.env.t.Helper()
+
+	for i, s := range steps {
+		switch {
+		case s.send != "" || s.contact != "":
+			c.message(s.send, s.contact)
+		case s.press != "":
+			c.press(s.press)
+		default:
+			c.callback(s.data, 0)
+		}
+
+		if s.want != "" && c.reply(s.want) == nil {
+			c.env.t.Fatalf("шаг %d, %s: нет ответа с %q, получено:\n%s", i+1, s, s.want, c.dump())
+		}
+		if s.wantAnswer != "" && (c.answer == nil || c.answer.Text != s.wantAnswer) {
+			got := "<нет>"
+			if c.answer != nil {
+				got = strconv.Quote(c.answer.Text)
+			}
+			c.env.t.Fatalf("шаг %d, %s: ответ на кнопку %s, ожидался %q", i+1, s, got, s.wantAnswer)
+		}
+	}
+}
+
+// message отправляет сообщение или контакт
+func (c *client) message(text, phone string) {
+	c.env.updateID++
+	message := &tgbotapi.Message{
+		MessageID: c.env.updateID,
+		From:      &tgbotapi.User{ID: c.userID, FirstName: "Тест"},
+		Chat:      &tgbotapi.Chat{ID: c.userID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		command := strings.Fields(text)[0]
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	if phone != "" {
+		message.Contact = &tgbotapi.Contact{PhoneNumber: phone, FirstName: "Тест", UserID: c.userID}
+	}
+
+	c.deliver(tgbotapi.Update{UpdateID: c.env.updateID, Message: message})
+}
+
+// press нажимает inline-кнопку с текстом label в самом новом сообщении клиента, где она есть
+func (c *client) press(label string) {
+	c.env.t.Helper()
+
+	for i := len(c.env.history) - 1; i >= 0; i-- {
+		m := c.env.history[i]
+		if m.ChatID != c.userID {
+			continue
+		}
+		if data, ok := m.Button(label); ok {
+			c.callback(data, m.MessageID)
+			return
+		}
+	}
+	c.env.t.Fatalf("кнопка %q не найдена", label)
+}
+
+// callback отправляет нажатие кнопки с данными data под сообщением messageID
+func (c *client) callback(data string, messageID int) {
+	c.env.updateID++
+	c.env.callbackID++
+
+	callback := &tgbotapi.CallbackQuery{
+		ID:   strconv.Itoa(c.env.callbackID),
+		From: &tgbotapi.User{ID: c.userID, FirstName: "Тест"},
+		Message: &tgbotapi.Message{
+			MessageID: messageID,
+			Chat:      &tgbotapi.Chat{ID: c.userID, Type: "private"},
+			Text:      c.env.texts[messageID],
+		},
+		Data: data,
+	}
+
+	c.deliver(tgbotapi.Update{UpdateID: c.env.updateID, CallbackQuery: callback})
+}
+
+// deliver передает обновление обработчику и собирает ответы бота
+func (c *client) deliver(update tgbotapi.Update) {
+	answers := len(c.env.fake.CallbackAnswers())
+
+	c.env.bot.handleUpdate(context.Background(), update)
+	c.receive()
+
+	c.answer = nil
+	if all := c.env.fake.CallbackAnswers(); len(all) > answers {
+		c.answer = &all[len(all)-1]
+	}
+}
+
+// receive собирает сообщения, отправленные ботом после предыдущего шага,
+// в том числе фоновыми задачами
+func (c *client) receive() {
+	c.replies = nil
+	for _, m := range c.env.fake.Take() {
+		c.env.history = append(c.env.history, m)
+		if !m.Edit {
+			c.env.texts[m.MessageID] = m.Text
+		}
+		if m.ChatID == c.userID {
+			c.replies = append(c.replies, m)
+		}
+	}
+}
+
+// reply возвращает ответ на последний шаг, содержащий text, или nil
+func (c *client) reply(text string) *messenger.Message {
+	for i := range c.replies {
+		if strings.Contains(c.replies[i].Text, text) {
+			return &c.replies[i]
+		}
+	}
+	return nil
+}
+
+func (c *client) dump() string {
+	var b strings.Builder
+	for _, m := range c.replies {
+		b.WriteString("---\n")
+		b.WriteString(m.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// session возвращает сохраненную сессию клиента
+func (c *client) session() *models.UserSession {
+	c.env.t.Helper()
+
+	session, err := c.env.store.GetUserSession(context.Background(), c.userID)
+	if err != nil {
+		c.env.t.Fatalf("получение сессии: %v", err)
+	}
+	return session
+}
+
+// request возвращает текущую заявку клиента
+func (c *client) request() *models.ServiceRequest {
+	c.env.t.Helper()
+
+	session := c.session()
+	if session.RequestID.IsZero() {
+		c.env.t.Fatalf("у сессии нет заявки")
+	}
+	request, err := c.env.store.GetServiceRequest(context.Background(), session.RequestID)
+	if err != nil {
+		c.env.t.Fatalf("получение заявки: %v", err)
+	}
+	return request
+}
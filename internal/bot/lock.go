@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// userLockTTL срок блокировки клиента. Пока обновление обрабатывается, блокировка продлевается,
+	// а если экземпляр упал, не сняв ее, другие экземпляры ждут не дольше этого срока.
+	userLockTTL = 30 * time.Second
+	// userLockRetry пауза между попытками захватить блокировку, занятую другим экземпляром
+	userLockRetry = 100 * time.Millisecond
+)
+
+// processUpdate обрабатывает обновление под блокировкой клиента в хранилище.
+// Диспетчер упорядочивает обновления клиента только внутри процесса, а блокировка не дает
+// нескольким экземплярам бота одновременно перезаписывать сессию и заявку одного клиента.
+func (b *Bot) processUpdate(update tgbotapi.Update) {
+	userID := updateKey(update)
+	if !b.lockUser(userID) {
+		b.logger.Error("Обновление %d не обработано: бот остановлен до захвата блокировки клиента %d", update.UpdateID, userID)
+		return
+	}
+	defer b.unlockUser(userID)
+
+	// Продлеваем блокировку, пока обработка не закончится
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(userLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := b.dbService.AcquireUserLock(b.ctx, userID, b.instanceID, userLockTTL); err != nil {
+					b.logger.Error("Ошибка продления блокировки клиента %d: %v", userID, err)
+				}
+			}
+		}
+	}()
+
+	b.handleUpdate(b.ctx, update)
+}
+
+// lockUser ждет, пока блокировка клиента освободится, и захватывает ее.
+// Возвращает false, если бот остановлен раньше. Если хранилище недоступно, обновление
+// обрабатывается без блокировки: обработчик сам сообщит клиенту об ошибке.
+func (b *Bot) lockUser(userID int64) bool {
+	for {
+		ok, err := b.dbService.AcquireUserLock(b.ctx, userID, b.instanceID, userLockTTL)
+		if err != nil {
+			if b.ctx.Err() != nil {
+				return false
+			}
+			b.logger.Error("Ошибка захвата блокировки клиента %d: %v", userID, err)
+			return true
+		}
+		if ok {
+			return true
+		}
+
+		select {
+		case <-b.ctx.Done():
+			return false
+		case <-time.After(userLockRetry):
+		}
+	}
+}
+
+func (b *Bot) unlockUser(userID int64) {
+	// Снимаем блокировку и после отмены контекста бота, иначе другие экземпляры ждали бы ее истечения
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.dbService.ReleaseUserLock(ctx, userID, b.instanceID); err != nil {
+		b.logger.Error("Ошибка снятия блокировки клиента %d: %v", userID, err)
+	}
+}
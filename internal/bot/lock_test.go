@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Обновление клиента, чью блокировку держит другой экземпляр бота, ждет ее снятия
+func TestUserLockAcrossInstances(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if ok, err := env.store.AcquireUserLock(ctx, testUserID, "другой экземпляр", time.Minute); !ok || err != nil {
+		t.Fatalf("захват блокировки: %v, %v", ok, err)
+	}
+
+	env.bot.updates.Dispatch(tgbotapi.Update{UpdateID: 1, Message: &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: testUserID, FirstName: "Тест"},
+		Chat:      &tgbotapi.Chat{ID: testUserID, Type: "private"},
+		Text:      "/start",
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 6}},
+	}})
+
+	time.Sleep(5 * userLockRetry)
+	if sent := env.fake.Take(); len(sent) > 0 {
+		t.Fatalf("бот ответил, пока блокировку держал другой экземпляр: %q", sent[0].Text)
+	}
+
+	if err := env.store.ReleaseUserLock(ctx, testUserID, "другой экземпляр"); err != nil {
+		t.Fatal(err)
+	}
+	env.bot.updates.Shutdown(ctx)
+
+	if sent := env.fake.Take(); len(sent) == 0 {
+		t.Fatal("бот не ответил после снятия блокировки")
+	}
+
+	// После обработки бот снимает свою блокировку
+	if ok, err := env.store.AcquireUserLock(ctx, testUserID, "другой экземпляр", time.Minute); !ok || err != nil {
+		t.Fatalf("блокировка не снята после обработки: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const sessionCheckInterval = time.Minute
+
+// idleStages этапы, на которых незаконченная заявка ждет клиента
+var idleStages = []int{
+	models.StageCarInfo,
+	models.StageProblemInfo,
+	models.StageReview,
+	models.StageDateSelection,
+}
+
+// SessionNudger напоминает клиентам о незаконченных заявках и сбрасывает заброшенные сессии
+type SessionNudger struct {
+	bot         *Bot
+	nudgeAfter  time.Duration
+	expireAfter time.Duration
+	stopChan    chan struct{}
+	done        chan struct{}
+
+	// Контекст запросов к базе, отменяется, если при остановке текущая проверка не успела завершиться
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewSessionNudger(b *Bot) *SessionNudger {
+	n := &SessionNudger{
+		bot:         b,
+		nudgeAfter:  b.config.SessionNudgeAfter,
+		expireAfter: b.config.SessionExpireAfter,
+		stopChan:    make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	n.ctx, n.cancel = context.WithCancel(b.ctx)
+	return n
+}
+
+func (n *SessionNudger) Start() {
+	defer close(n.done)
+
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+
+	n.checkIdleSessions()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.checkIdleSessions()
+		case <-n.stopChan:
+			return
+		}
+	}
+}
+
+// Stop останавливает проверки и дожидается окончания текущей, но не дольше ctx
+func (n *SessionNudger) Stop(ctx context.Context) {
+	stopWorker(ctx, n.stopChan, n.done, n.cancel)
+	n.bot.logger.Info("Проверка незаконченных заявок остановлена")
+}
+
+func (n *SessionNudger) checkIdleSessions() {
+	ctx := n.ctx
+	now := time.Now()
+
+	// Сначала сбрасываем просроченные сессии, чтобы по ним не ушло напоминание
+	if n.expireAfter > 0 {
+		before := now.Add(-n.expireAfter)
+		sessions, err := n.bot.dbService.GetIdleSessions(ctx, idleStages, before)
+		if err != nil {
+			n.bot.logger.Error("Ошибка получения просроченных сессий: %v", err)
+		}
+		for _, session := range sessions {
+			n.expire(ctx, session, before)
+		}
+	}
+
+	if n.nudgeAfter > 0 && (n.expireAfter == 0 || n.nudgeAfter < n.expireAfter) {
+		before := now.Add(-n.nudgeAfter)
+		sessions, err := n.bot.dbService.GetIdleSessions(ctx, idleStages, before)
+		if err != nil {
+			n.bot.logger.Error("Ошибка получения неактивных сессий: %v", err)
+		}
+		for _, session := range sessions {
+			if session.NudgedAt == nil {
+				n.nudge(ctx, session, before)
+			}
+		}
+	}
+}
+
+// nudge один раз предлагает клиенту продолжить заполнение заявки
+func (n *SessionNudger) nudge(ctx context.Context, session *models.UserSession, before time.Time) {
+	if session.ChatID == 0 || session.RequestID.IsZero() {
+		return
+	}
+
+	// Перенос существующей записи не напоминаем: запись от этого не пропадет
+	request, err := n.bot.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil || request.Status != models.StatusDraft {
+		return
+	}
+
+	marked, err := n.bot.dbService.MarkSessionNudged(ctx, session.UserID, before)
+	if err != nil {
+		n.bot.logger.Error("Ошибка отметки напоминания о заявке: %v", err)
+		return
+	}
+	if !marked {
+		return
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("▶️ Продолжить", "resume_continue_"+request.ID.Hex())),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("❌ Отменить заявку", "resume_cancel_"+request.ID.Hex())),
+	)
+
+	if err := n.bot.messenger.SendKeyboard(session.ChatID, "Вы не закончили заявку на обслуживание. Продолжим?", keyboard); err != nil {
+		n.bot.logger.Error("Ошибка отправки напоминания о заявке: %v", err)
+		return
+	}
+
+	n.bot.logger.Info("Отправлено напоминание о незаконченной заявке пользователю %d", session.UserID)
+}
+
+// expire сбрасывает заброшенную сессию и отменяет ее черновик заявки
+func (n *SessionNudger) expire(ctx context.Context, session *models.UserSession, before time.Time) {
+	expired, err := n.bot.dbService.ExpireUserSession(ctx, session.UserID, before)
+	if err != nil {
+		n.bot.logger.Error("Ошибка сброса сессии: %v", err)
+		return
+	}
+	if !expired {
+		return
+	}
+
+	text := "Заявка не была заполнена до конца и отменена. Чтобы записаться, нажмите /start."
+
+	if !session.RequestID.IsZero() {
+		request, err := n.bot.dbService.GetServiceRequest(ctx, session.RequestID)
+		switch {
+		case err != nil:
+			n.bot.logger.Error("Ошибка получения заявки: %v", err)
+		case request.Status == models.StatusDraft:
+			if err := n.bot.dbService.TransitionServiceRequest(ctx, request, models.StatusCancelled, services.SystemActor, "заявка не заполнена вовремя"); err != nil {
+				n.bot.logger.Error("Ошибка отмены черновика заявки: %v", err)
+			}
+		default:
+			// Перенос не завершен, прежняя запись остается в силе
+			text = "Перенос записи не был завершен, ваша запись остается в силе. Посмотреть записи можно командой /my."
+		}
+	}
+
+	if session.ChatID != 0 {
+		n.bot.sendMessage(session.ChatID, text)
+	}
+	n.bot.logger.Info("Сброшена неактивная сессия пользователя %d", session.UserID)
+}
+
+// resetNudge снимает отметку о напоминании: клиент вернулся к заявке
+func (b *Bot) resetNudge(ctx context.Context, session *models.UserSession) {
+	if session.NudgedAt == nil {
+		return
+	}
+	session.NudgedAt = nil
+	if err := b.dbService.SaveUserSession(ctx, session); err != nil {
+		b.logger.Error("Ошибка сохранения сессии: %v", err)
+	}
+}
+
+// handleResumeCallback обрабатывает кнопки напоминания о незаконченной заявке.
+// Кнопки действуют, только пока сессия заполняет тот же черновик, о котором было напоминание.
+func (b *Bot) handleResumeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	action, rawID, _ := strings.Cut(strings.TrimPrefix(callback.Data, "resume_"), "_")
+	requestID, err := primitive.ObjectIDFromHex(rawID)
+	if err != nil || !isIdleStage(session.Stage) || session.RequestID != requestID {
+		b.answerCallback(callback.ID, "Эта заявка уже неактуальна")
+		return
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, requestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+	if request.Status != models.StatusDraft {
+		// Черновик уже отменен или записан, сессию по нему продолжать нельзя
+		b.answerCallback(callback.ID, "Эта заявка уже неактуальна")
+		b.resetSession(ctx, session)
+		return
+	}
+
+	switch action {
+	case "continue":
+		b.answerCallback(callback.ID, "")
+		b.editMessage(chatID, callback.Message.MessageID, "Продолжаем заполнение заявки.")
+		b.resumeSession(ctx, chatID, session)
+
+	case "cancel":
+		b.answerCallback(callback.ID, "Заявка отменена")
+		b.editMessage(chatID, callback.Message.MessageID, callback.Message.Text)
+		b.cancelSession(ctx, chatID, callback.From.ID, session, "отмена после напоминания о незаконченной заявке")
+
+	default:
+		b.answerCallback(callback.ID, "Неизвестный callback")
+	}
+}
+
+// resumeSession повторяет клиенту текущий шаг заявки
+func (b *Bot) resumeSession(ctx context.Context, chatID int64, session *models.UserSession) {
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	switch session.Stage {
+	case models.StageReview:
+		b.showReview(ctx, chatID, session, request)
+
+	case models.StageDateSelection:
+		b.showAvailableDates(ctx, chatID)
+
+	default:
+		q := b.questions.Question(session.QuestionID)
+		if q == nil {
+			b.sendMessage(chatID, "Анкета была изменена. Нажмите /start, чтобы заполнить заявку заново.")
+			return
+		}
+
+		// Предложения гаража и контактов повторять не нужно, задаем сам вопрос
+		delete(session.Data, contactOfferKey)
+		delete(session.Data, garageOfferKey)
+		if !b.saveProgress(ctx, chatID, session, request, q) {
+			return
+		}
+		b.askQuestion(ctx, chatID, q, currentAnswerNote(request, q))
+	}
+}
+
+func isIdleStage(stage int) bool {
+	for _, s := range idleStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/services"
+)
+
+// nudge отправляет напоминания о незаконченных заявках всем неактивным клиентам
+func (e *testEnv) nudge() {
+	e.bot.config.SessionNudgeAfter = time.Nanosecond
+	e.bot.config.SessionExpireAfter = 0
+	time.Sleep(time.Millisecond)
+	NewSessionNudger(e.bot).checkIdleSessions()
+}
+
+func TestScenarioResumeButtons(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(1, "10:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+	c.run(
+		step{press: "✅ Подтвердить"},
+		step{press: dateButton(date)},
+		step{press: "10:00", want: "Заявка успешно создана"},
+	)
+	booked := c.request()
+
+	// Клиент начал вторую заявку и бросил ее на вопросах об автомобиле
+	c.run(
+		step{send: "/start", want: "Продолжить как Иван"},
+		step{press: "✅ Да, это я", want: "гараж"},
+	)
+	draft := c.request()
+
+	env.nudge()
+	c.receive()
+	if c.reply("Вы не закончили заявку") == nil {
+		t.Fatalf("напоминание о заявке не отправлено:\n%s", c.dump())
+	}
+
+	c.run(step{press: "❌ Отменить заявку", wantAnswer: "Заявка отменена", want: "Заявка отменена"})
+	if cancelled, _ := env.store.GetServiceRequest(context.Background(), draft.ID); cancelled.Status != models.StatusCancelled {
+		t.Fatalf("черновик не отменен: %q", cancelled.Status)
+	}
+
+	// Клиент переносит запись через /my
+	c.run(
+		step{send: "/my", want: "XC60"},
+		step{press: "🔁 Перенести", want: "Выберите новую дату"},
+	)
+
+	// Кнопки старого напоминания и кнопки без ID заявки не трогают запись
+	c.run(
+		step{data: "resume_cancel_" + draft.ID.Hex(), wantAnswer: "Эта заявка уже неактуальна"},
+		step{data: "resume_cancel", wantAnswer: "Эта заявка уже неактуальна"},
+		step{data: "resume_cancel_" + booked.ID.Hex(), wantAnswer: "Эта заявка уже неактуальна"},
+	)
+
+	requests, _ := env.store.GetServiceRequests(context.Background(), services.RequestFilter{UserID: testUserID, Statuses: []string{models.StatusBooked}})
+	if len(requests) != 1 || requests[0].ID != booked.ID {
+		t.Fatalf("запись отменена кнопкой напоминания: %+v", requests)
+	}
+	slots, _ := env.store.GetAvailableDateByID(context.Background(), date.ID)
+	if !slots.TimeSlots[0].IsBooked {
+		t.Error("слот записи освобожден")
+	}
+}
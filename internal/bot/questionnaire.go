@@ -0,0 +1,463 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/questionnaire"
+	"volvomaster/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sectionStages связывает разделы анкеты с этапами сессии.
+// Вопросы из остальных разделов относятся к этапу информации о проблеме.
+var sectionStages = map[string]int{
+	"personal": models.StagePersonalInfo,
+	"car":      models.StageCarInfo,
+	"problem":  models.StageProblemInfo,
+}
+
+func sectionStage(section string) int {
+	if stage, ok := sectionStages[section]; ok {
+		return stage
+	}
+	return models.StageProblemInfo
+}
+
+// startQuestionnaire создает черновик заявки и задает первый вопрос анкеты
+func (b *Bot) startQuestionnaire(ctx context.Context, chatID, userID int64, session *models.UserSession, greeting string) {
+	// Незаконченный черновик больше не нужен
+	if !session.RequestID.IsZero() {
+		previous, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+		if err == nil && previous.Status == models.StatusDraft {
+			if err := b.dbService.TransitionServiceRequest(ctx, previous, models.StatusCancelled, services.CustomerActor(userID), "начата новая заявка"); err != nil {
+				b.logger.Error("Ошибка отмены черновика заявки: %v", err)
+			}
+		}
+	}
+
+	first := b.questions.First()
+	request := &models.ServiceRequest{
+		UserID: userID,
+		ChatID: chatID,
+		Stage:  sectionStage(first.Section),
+	}
+
+	if err := b.dbService.TransitionServiceRequest(ctx, request, models.StatusDraft, services.CustomerActor(userID), ""); err != nil {
+		b.logger.Error("Ошибка сохранения заявки: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	session.Stage = request.Stage
+	session.RequestID = request.ID
+	session.QuestionID = first.ID
+	session.Data = make(map[string]interface{})
+
+	if err := b.dbService.SaveUserSession(ctx, session); err != nil {
+		b.logger.Error("Ошибка сохранения сессии: %v", err)
+	}
+
+	intro := greeting
+	if section := b.questions.Section(first.Section); section != nil && section.Intro != "" {
+		intro = strings.TrimSpace(intro + "\n\n" + section.Intro)
+	}
+
+	// Постоянному клиенту не нужно заново вводить имя и телефон
+	if first.Section == personalSection && b.offerKnownContact(ctx, chatID, userID, session, intro) {
+		return
+	}
+	b.askQuestion(ctx, chatID, first, intro)
+}
+
+// backButtonText текст кнопки возврата к предыдущему вопросу
+const backButtonText = "⬅️ Назад"
+
+// askQuestion отправляет вопрос анкеты, для вопросов с выбором - с кнопками вариантов.
+// Кроме первого вопроса, у каждого есть кнопка «Назад».
+func (b *Bot) askQuestion(ctx context.Context, chatID int64, q *questionnaire.Question, intro string) {
+	text := q.Text
+	if intro != "" {
+		text = intro + "\n\n" + text
+	}
+
+	back := q.ID != b.questions.First().ID
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	if q.Type == questionnaire.InputChoice {
+		for _, option := range q.Options {
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(option.Label, questionnaire.CallbackData(q.ID, option.Key)),
+			))
+		}
+	}
+
+	// Модель удобнее выбрать из каталога, чем написать
+	if q.Validation != nil && q.Validation.Format == questionnaire.FormatVolvoModel {
+		keyboard = append(keyboard, b.modelKeyboard(ctx)...)
+	}
+
+	if q.Optional {
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Пропустить", questionnaire.SkipCallbackData(q.ID)),
+		))
+	}
+
+	var reply interface{}
+	if len(keyboard) == 0 && q.Type == questionnaire.InputContact {
+		// Кнопка отправки номера бывает только в обычной клавиатуре, туда же добавляем «Назад»
+		markup := contactKeyboard()
+		if back {
+			markup.Keyboard = append(markup.Keyboard, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(backButtonText)))
+		}
+		reply = markup
+	} else {
+		if back {
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(backButtonText, "back_"+q.ID),
+			))
+		}
+		if len(keyboard) > 0 {
+			reply = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+		}
+	}
+
+	if err := b.messenger.SendKeyboard(chatID, text, reply); err != nil {
+		b.logger.Error("Ошибка отправки сообщения: %v", err)
+	}
+}
+
+// handleQuestionnaireMessage обрабатывает ответ на текущий вопрос анкеты, присланный сообщением
+func (b *Bot) handleQuestionnaireMessage(ctx context.Context, message *tgbotapi.Message, session *models.UserSession) {
+	chatID := message.Chat.ID
+
+	q := b.questions.Question(session.QuestionID)
+	if q == nil {
+		b.sendMessage(chatID, "Анкета была изменена. Нажмите /start, чтобы заполнить заявку заново.")
+		return
+	}
+
+	if offered, _ := session.Data[contactOfferKey].(bool); offered {
+		b.sendMessage(chatID, "Пожалуйста, выберите вариант из предложенных выше.")
+		return
+	}
+	if offered, _ := session.Data[garageOfferKey].(bool); offered {
+		b.sendMessage(chatID, "Пожалуйста, выберите автомобиль из списка выше или нажмите «Другой автомобиль».")
+		return
+	}
+
+	if attachment := attachmentFromMessage(message); attachment != nil && q.Type != questionnaire.InputPhoto {
+		// Фото, видео и голосовые сообщения прикладываются к описанию проблемы
+		if session.Stage == models.StageProblemInfo {
+			b.addAttachment(ctx, chatID, session, attachment)
+		} else {
+			b.sendMessage(chatID, "Фото, видео и голосовые сообщения можно будет приложить, когда дойдем до описания проблемы. Сейчас, пожалуйста, ответьте на вопрос текстом.")
+		}
+		return
+	}
+
+	var option *questionnaire.Option
+	answer := strings.TrimSpace(message.Text)
+
+	// «Назад» из обычной клавиатуры вопроса о контакте
+	if answer == backButtonText {
+		b.handleBack(ctx, chatID, message.From.ID, session, q)
+		return
+	}
+
+	if q.Optional && strings.EqualFold(answer, "пропустить") {
+		b.applyAnswer(ctx, chatID, message.From.ID, session, q, nil, "")
+		return
+	}
+
+	switch q.Type {
+	case questionnaire.InputChoice:
+		// Разрешаем написать вариант вручную
+		option = q.OptionByLabel(answer)
+		if option == nil {
+			b.sendMessage(chatID, "Пожалуйста, выберите вариант из предложенных выше.")
+			return
+		}
+		answer = option.Label
+
+	case questionnaire.InputContact:
+		if message.Contact != nil {
+			answer = message.Contact.PhoneNumber
+		}
+
+	case questionnaire.InputPhoto:
+		if len(message.Photo) == 0 {
+			b.sendMessage(chatID, "Пожалуйста, отправьте фотографию.")
+			return
+		}
+		// Telegram присылает несколько размеров, сохраняем самый крупный
+		answer = message.Photo[len(message.Photo)-1].FileID
+	}
+
+	if answer == "" {
+		b.sendMessage(chatID, "Пожалуйста, ответьте на вопрос текстом.")
+		return
+	}
+
+	if q.Type != questionnaire.InputPhoto {
+		if err := q.Check(answer); err != nil {
+			b.sendMessage(chatID, err.Error())
+			return
+		}
+	}
+
+	b.applyAnswer(ctx, chatID, message.From.ID, session, q, option, answer)
+}
+
+// handleQuestionnaireCallback обрабатывает выбор варианта ответа кнопкой
+func (b *Bot) handleQuestionnaireCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	questionID, optionKey, ok := questionnaire.ParseCallbackData(callback.Data)
+	if !ok {
+		b.answerCallback(callback.ID, "Неизвестный callback")
+		return
+	}
+
+	// Кнопки старых вопросов больше не действуют
+	if session.QuestionID != questionID {
+		b.answerCallback(callback.ID, "Этот вопрос уже неактуален")
+		return
+	}
+
+	q := b.questions.Question(questionID)
+	if q == nil {
+		b.answerCallback(callback.ID, "Этот вопрос уже неактуален")
+		return
+	}
+
+	// Пустой ключ - кнопка «Пропустить»
+	if optionKey == "" && q.Optional {
+		b.answerCallback(callback.ID, "")
+		b.applyAnswer(ctx, callback.Message.Chat.ID, callback.From.ID, session, q, nil, "")
+		return
+	}
+
+	option := q.Option(optionKey)
+	if option == nil {
+		b.answerCallback(callback.ID, "Неизвестный вариант ответа")
+		return
+	}
+
+	b.answerCallback(callback.ID, "")
+	b.applyAnswer(ctx, callback.Message.Chat.ID, callback.From.ID, session, q, option, option.Label)
+}
+
+// handleBackCallback обрабатывает кнопку «Назад» под вопросом
+func (b *Bot) handleBackCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	questionID := strings.TrimPrefix(callback.Data, "back_")
+
+	q := b.questions.Question(questionID)
+	if q == nil || session.QuestionID != questionID {
+		b.answerCallback(callback.ID, "Этот вопрос уже неактуален")
+		return
+	}
+
+	b.answerCallback(callback.ID, "")
+	b.handleBack(ctx, callback.Message.Chat.ID, callback.From.ID, session, q)
+}
+
+// handleBack возвращает клиента к предыдущему вопросу анкеты, чтобы исправить ответ
+func (b *Bot) handleBack(ctx context.Context, chatID, userID int64, session *models.UserSession, q *questionnaire.Question) {
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	prev := b.previousQuestion(request, q)
+	if prev == nil {
+		b.sendMessage(chatID, "Это первый вопрос анкеты.")
+		return
+	}
+
+	delete(session.Data, contactOfferKey)
+	delete(session.Data, garageOfferKey)
+	delete(session.Data, otherModelKey)
+
+	if !b.saveProgress(ctx, chatID, session, request, prev) {
+		return
+	}
+	b.askQuestion(ctx, chatID, prev, currentAnswerNote(request, prev))
+}
+
+// applyAnswer сохраняет ответ в заявке и переходит к следующему вопросу или к проверке заявки
+func (b *Bot) applyAnswer(ctx context.Context, chatID, userID int64, session *models.UserSession, q *questionnaire.Question, option *questionnaire.Option, answer string) {
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	// Пустой ответ означает, что необязательный вопрос пропущен
+	if answer != "" && q.Validation != nil && q.Validation.Format != "" {
+		answer, err = b.parseAnswerFormat(ctx, session, request, q.Validation.Format, answer)
+		if err != nil {
+			// Остаемся на том же вопросе, клиент ответит еще раз
+			b.sendMessage(chatID, err.Error())
+			return
+		}
+	}
+
+	if !request.SetAnswer(q.ID, q.Field, answer) {
+		b.logger.Error("Вопрос %s ссылается на неизвестное поле заявки %s", q.ID, q.Field)
+	}
+
+	if q.Field == "contact" {
+		b.updateUserContact(ctx, userID, request)
+	}
+	if q.Type == questionnaire.InputContact {
+		b.removeReplyKeyboard(chatID, "👍 Контакт сохранен")
+	}
+
+	b.moveToQuestion(ctx, chatID, userID, session, request, q, b.nextQuestion(request, q, option), "")
+}
+
+// moveToQuestion сохраняет заявку и задает вопрос next; если вопросов больше нет, показывает заявку на проверку.
+// prev - предыдущий вопрос, при переходе в новый раздел перед вопросом показывается вступление раздела.
+func (b *Bot) moveToQuestion(ctx context.Context, chatID, userID int64, session *models.UserSession, request *models.ServiceRequest, prev, next *questionnaire.Question, intro string) {
+	if next == nil {
+		// Анкета заполнена, перед выбором даты клиент проверяет ответы
+		b.showReview(ctx, chatID, session, request)
+		return
+	}
+
+	if !b.saveProgress(ctx, chatID, session, request, next) {
+		return
+	}
+
+	// Вступление показываем, только когда клиент впервые приступает к разделу,
+	// а не возвращается в него исправить ответ
+	if (prev == nil || next.Section != prev.Section) && !b.sectionStarted(request, next.Section) {
+		if section := b.questions.Section(next.Section); section != nil && section.Intro != "" {
+			intro = strings.TrimSpace(section.Intro + "\n\n" + intro)
+		}
+
+		// Постоянному клиенту предлагаем выбрать автомобиль из гаража
+		if next.Section == carSection && b.offerGarage(ctx, chatID, userID, session, intro) {
+			return
+		}
+	}
+
+	if note := currentAnswerNote(request, next); note != "" {
+		intro = strings.TrimSpace(intro + "\n\n" + note)
+	}
+	b.askQuestion(ctx, chatID, next, intro)
+}
+
+// saveProgress сохраняет заявку и делает q текущим вопросом сессии
+func (b *Bot) saveProgress(ctx context.Context, chatID int64, session *models.UserSession, request *models.ServiceRequest, q *questionnaire.Question) bool {
+	request.Stage = sectionStage(q.Section)
+
+	if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
+		b.logger.Error("Ошибка сохранения заявки: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return false
+	}
+
+	session.Stage = request.Stage
+	session.QuestionID = q.ID
+	b.dbService.SaveUserSession(ctx, session)
+	return true
+}
+
+// previousQuestion возвращает вопрос, который в анкете задается перед q при текущих ответах, или nil
+func (b *Bot) previousQuestion(request *models.ServiceRequest, q *questionnaire.Question) *questionnaire.Question {
+	path := b.questions.Path(request.Answers)
+	for i, step := range path {
+		if step.ID == q.ID && i > 0 {
+			return path[i-1]
+		}
+	}
+	return nil
+}
+
+// sectionStarted проверяет, есть ли в заявке ответы на вопросы раздела
+func (b *Bot) sectionStarted(request *models.ServiceRequest, section string) bool {
+	for questionID := range request.Answers {
+		if q := b.questions.Question(questionID); q != nil && q.Section == section {
+			return true
+		}
+	}
+	return false
+}
+
+// currentAnswerNote напоминает клиенту ответ, который он уже дал на вопрос
+func currentAnswerNote(request *models.ServiceRequest, q *questionnaire.Question) string {
+	answer, answered := request.Answers[q.ID]
+	if !answered {
+		return ""
+	}
+	return "Текущий ответ: " + answerText(q, answer)
+}
+
+// answerText возвращает ответ в том виде, в котором его показывают клиенту
+func answerText(q *questionnaire.Question, answer string) string {
+	switch {
+	case answer == "":
+		return "пропущено"
+	case q.Type == questionnaire.InputPhoto:
+		return "фото"
+	}
+	return answer
+}
+
+// nextQuestion возвращает следующий вопрос анкеты, пропуская вопросы, ответы на которые
+// уже заполнены (например, по VIN или до возврата к предыдущему вопросу)
+func (b *Bot) nextQuestion(request *models.ServiceRequest, q *questionnaire.Question, option *questionnaire.Option) *questionnaire.Question {
+	return b.skipAnswered(request, b.questions.Next(q, option))
+}
+
+// skipAnswered возвращает первый неотвеченный вопрос, начиная с q
+func (b *Bot) skipAnswered(request *models.ServiceRequest, q *questionnaire.Question) *questionnaire.Question {
+	for q != nil {
+		answer, answered := request.Answers[q.ID]
+		if !answered {
+			break
+		}
+		q = b.questions.Next(q, q.OptionByLabel(answer))
+	}
+	return q
+}
+
+// prefillField заполняет поле заявки и отмечает соответствующий вопрос анкеты отвеченным
+func (b *Bot) prefillField(request *models.ServiceRequest, field, value string) {
+	if q := b.questions.QuestionByField(field); q != nil {
+		request.SetAnswer(q.ID, field, value)
+		return
+	}
+	request.SetField(field, value)
+}
+
+// updateUserContact сохраняет указанный в анкете телефон или Telegram в профиле пользователя
+func (b *Bot) updateUserContact(ctx context.Context, userID int64, request *models.ServiceRequest) {
+	user, err := b.dbService.GetUser(ctx, userID)
+	if err != nil {
+		b.logger.Error("Ошибка получения пользователя для обновления: %v", err)
+		return
+	}
+
+	if request.Name != "" {
+		user.Name = request.Name
+	}
+
+	switch {
+	case request.Phone != "":
+		user.Phone = request.Phone
+	case strings.HasPrefix(request.Contact, "@"):
+		user.Telegram = request.Contact
+	default:
+		user.Phone = request.Contact
+	}
+
+	if err := b.dbService.SaveUser(ctx, user); err != nil {
+		b.logger.Error("Ошибка обновления пользователя: %v", err)
+	}
+}
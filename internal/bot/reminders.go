@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"volvomaster/internal/models"
+)
+
+const reminderCheckInterval = time.Minute
+
+// reminder описывает напоминание, отправляемое за offset до записи
+type reminder struct {
+	kind   string
+	offset time.Duration
+}
+
+// reminders упорядочены по убыванию offset
+var reminders = []reminder{
+	{kind: "24h", offset: 24 * time.Hour},
+	{kind: "2h", offset: 2 * time.Hour},
+}
+
+// ReminderScheduler периодически отправляет клиентам напоминания о записи
+type ReminderScheduler struct {
+	bot      *Bot
+	location *time.Location
+	stopChan chan struct{}
+	done     chan struct{}
+
+	// Контекст запросов к базе, отменяется, если при остановке текущая проверка не успела завершиться
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewReminderScheduler(b *Bot, location *time.Location) *ReminderScheduler {
+	s := &ReminderScheduler{
+		bot:      b,
+		location: location,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(b.ctx)
+	return s
+}
+
+func (s *ReminderScheduler) Start() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	s.sendDueReminders()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sendDueReminders()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// Stop останавливает проверки и дожидается окончания текущей, но не дольше ctx
+func (s *ReminderScheduler) Stop(ctx context.Context) {
+	stopWorker(ctx, s.stopChan, s.done, s.cancel)
+	s.bot.logger.Info("Планировщик напоминаний остановлен")
+}
+
+func (s *ReminderScheduler) sendDueReminders() {
+	ctx := s.ctx
+	now := s.now()
+
+	for i, r := range reminders {
+		// Нижняя граница окна - время следующего (более позднего) напоминания,
+		// чтобы для недавно созданной записи не уходили сразу оба напоминания
+		from := now
+		if i+1 < len(reminders) {
+			from = now.Add(reminders[i+1].offset)
+		}
+
+		requests, err := s.bot.dbService.GetRequestsDueForReminder(ctx, r.kind, from, now.Add(r.offset))
+		if err != nil {
+			s.bot.logger.Error("Ошибка получения заявок для напоминания: %v", err)
+			continue
+		}
+
+		for _, request := range requests {
+			s.sendReminder(ctx, request, r, now)
+		}
+	}
+}
+
+func (s *ReminderScheduler) sendReminder(ctx context.Context, request *models.ServiceRequest, r reminder, now time.Time) {
+	// Сначала отмечаем напоминание, чтобы после перезапуска оно не ушло повторно
+	marked, err := s.bot.dbService.MarkReminderSent(ctx, request.ID, r.kind)
+	if err != nil {
+		s.bot.logger.Error("Ошибка отметки напоминания: %v", err)
+		return
+	}
+	if !marked {
+		return
+	}
+
+	text := fmt.Sprintf(`%s
+
+📅 Дата записи: %s
+🚗 Модель: %s %s
+
+Если планы изменились, перенесите или отмените запись командой /my.`,
+		reminderText(request.AppointmentDate, now), request.AppointmentDate.Format("02.01.2006 в 15:04"), request.VolvoModel, request.Year)
+
+	// Пока клиент не подтвердил запись, предлагаем сделать это из напоминания
+	var keyboard interface{}
+	if request.Status != models.StatusConfirmed {
+		keyboard = confirmationKeyboard(request.ID)
+	}
+
+	if err := s.bot.messenger.SendKeyboard(request.ChatID, text, keyboard); err != nil {
+		s.bot.logger.Error("Ошибка отправки напоминания: %v", err)
+		// Снимаем отметку, чтобы повторить попытку при следующей проверке
+		if err := s.bot.dbService.UnmarkReminderSent(ctx, request.ID, r.kind); err != nil {
+			s.bot.logger.Error("Ошибка снятия отметки напоминания: %v", err)
+		}
+		return
+	}
+
+	s.bot.logger.Info("Отправлено напоминание %s по заявке %s", r.kind, request.ID.Hex())
+}
+
+// reminderText называет время записи относительно now: запись на сегодня может попасть
+// в окно напоминания за сутки, а напоминание за 2 часа - уйти с опозданием после перезапуска
+func reminderText(appointment, now time.Time) string {
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()) }
+
+	when := appointment.Format("02.01 в 15:04")
+	switch day(appointment).Sub(day(now)) {
+	case 0:
+		when = appointment.Format("сегодня в 15:04")
+	case 24 * time.Hour:
+		when = appointment.Format("завтра в 15:04")
+	}
+	return fmt.Sprintf("⏰ Напоминаем, что %s вы записаны в сервисный центр Volvo.", when)
+}
+
+// now возвращает текущее время сервиса в виде "настенного" времени в UTC,
+// так как время записи хранится без учета часового пояса
+func (s *ReminderScheduler) now() time.Time {
+	local := time.Now().In(s.location)
+	return time.Date(local.Year(), local.Month(), local.Day(),
+		local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+}
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminderText(t *testing.T) {
+	now := time.Date(2024, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		appointment time.Time
+		want        string
+	}{
+		{"сегодня", time.Date(2024, 3, 14, 15, 0, 0, 0, time.UTC), "сегодня в 15:00"},
+		{"завтра", time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), "завтра в 10:00"},
+		{"позже", time.Date(2024, 3, 16, 10, 0, 0, 0, time.UTC), "16.03 в 10:00"},
+		{"конец месяца", time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC), "01.04 в 08:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := "⏰ Напоминаем, что " + tt.want + " вы записаны в сервисный центр Volvo."
+			if got := reminderText(tt.appointment, now); got != want {
+				t.Errorf("reminderText() = %q, ожидалось %q", got, want)
+			}
+		})
+	}
+
+	// После полуночи запись на утро - уже «сегодня»
+	late := time.Date(2024, 3, 14, 23, 50, 0, 0, time.UTC)
+	if got := reminderText(time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), late.Add(20*time.Minute)); got != "⏰ Напоминаем, что сегодня в 10:00 вы записаны в сервисный центр Volvo." {
+		t.Errorf("reminderText() после полуночи = %q", got)
+	}
+}
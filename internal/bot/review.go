@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/questionnaire"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// showReview показывает клиенту сводку заявки перед выбором даты с кнопками «Подтвердить» и «Изменить»
+func (b *Bot) showReview(ctx context.Context, chatID int64, session *models.UserSession, request *models.ServiceRequest) {
+	b.dropUnusedAnswers(request, b.questions.Path(request.Answers))
+
+	// Заявка изменилась, прежнее подтверждение больше не действует
+	request.ApprovedAt = nil
+	request.ApprovedSummary = ""
+	request.Stage = models.StageReview
+	if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
+		b.logger.Error("Ошибка сохранения заявки: %v", err)
+		b.sendMessage(chatID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	session.Stage = models.StageReview
+	session.QuestionID = ""
+	b.dbService.SaveUserSession(ctx, session)
+
+	text := "Проверьте, пожалуйста, заявку:\n\n" + b.requestSummary(request) + "\n\nВсе верно?"
+
+	if err := b.messenger.SendKeyboard(chatID, text, reviewKeyboard()); err != nil {
+		b.logger.Error("Ошибка отправки сообщения: %v", err)
+	}
+}
+
+// requestSummary возвращает все ответы заявки, сгруппированные по разделам анкеты
+func (b *Bot) requestSummary(request *models.ServiceRequest) string {
+	var blocks []string
+	var lines []string
+	section := ""
+
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		title := section
+		if s := b.questions.Section(section); s != nil && s.Title != "" {
+			title = s.Title
+		}
+		blocks = append(blocks, title+"\n"+strings.Join(lines, "\n"))
+		lines = nil
+	}
+
+	for _, q := range b.questions.Path(request.Answers) {
+		answer, answered := request.Answers[q.ID]
+		if !answered {
+			continue
+		}
+		if q.Section != section {
+			flush()
+			section = q.Section
+		}
+		lines = append(lines, fmt.Sprintf("• %s: %s", q.Label(), answerText(q, answer)))
+	}
+	if len(request.Attachments) > 0 {
+		lines = append(lines, fmt.Sprintf("• Вложения: %d", len(request.Attachments)))
+	}
+	flush()
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// reviewKeyboard кнопки сводки заявки
+func reviewKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", "review_confirm")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", "review_edit")),
+	)
+}
+
+// editAnswersKeyboard кнопки исправления отдельных ответов
+func (b *Bot) editAnswersKeyboard(request *models.ServiceRequest) tgbotapi.InlineKeyboardMarkup {
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, q := range b.questions.Path(request.Answers) {
+		if _, answered := request.Answers[q.ID]; !answered {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("✏️ "+q.Label(), "edit_"+q.ID))
+		if len(row) == 2 {
+			keyboard = append(keyboard, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+
+	keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(backButtonText, "review_back"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// dropUnusedAnswers удаляет ответы на вопросы, которые не входят в анкету при текущих ответах,
+// например объем двигателя после того, как клиент исправил тип двигателя на электро
+func (b *Bot) dropUnusedAnswers(request *models.ServiceRequest, path []*questionnaire.Question) {
+	used := make(map[string]bool, len(path))
+	for _, q := range path {
+		used[q.ID] = true
+	}
+
+	for _, q := range b.questions.Questions {
+		if _, answered := request.Answers[q.ID]; answered && !used[q.ID] {
+			request.ClearAnswer(q.ID, q.Field)
+		}
+	}
+}
+
+// handleEditAnswer обрабатывает кнопку исправления ответа на экране проверки заявки
+func (b *Bot) handleEditAnswer(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	q := b.questions.Question(strings.TrimPrefix(callback.Data, "edit_"))
+	if q == nil || session.Stage != models.StageReview {
+		b.answerCallback(callback.ID, "Эта заявка уже неактуальна")
+		return
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	delete(session.Data, otherModelKey)
+	if !b.saveProgress(ctx, chatID, session, request, q) {
+		b.answerCallback(callback.ID, "")
+		return
+	}
+
+	b.answerCallback(callback.ID, "")
+	// После ответа анкета пропустит заполненные вопросы и вернется к проверке
+	b.askQuestion(ctx, chatID, q, currentAnswerNote(request, q))
+}
+
+// handleReviewCallback обрабатывает кнопки сводки заявки
+func (b *Bot) handleReviewCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession) {
+	chatID := callback.Message.Chat.ID
+
+	if session.Stage != models.StageReview {
+		b.answerCallback(callback.ID, "Эта заявка уже неактуальна")
+		return
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, session.RequestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	switch callback.Data {
+	case "review_confirm":
+		b.confirmReview(ctx, callback, session, request)
+		return
+
+	case "review_edit":
+		// Под сводкой показываем кнопки отдельных ответов
+		if err := b.messenger.EditKeyboard(chatID, callback.Message.MessageID, b.editAnswersKeyboard(request)); err != nil {
+			b.logger.Error("Ошибка редактирования сообщения: %v", err)
+		}
+
+	case "review_back":
+		if err := b.messenger.EditKeyboard(chatID, callback.Message.MessageID, reviewKeyboard()); err != nil {
+			b.logger.Error("Ошибка редактирования сообщения: %v", err)
+		}
+
+	default:
+		b.answerCallback(callback.ID, "Неизвестный callback")
+		return
+	}
+	b.answerCallback(callback.ID, "")
+}
+
+// confirmReview записывает в заявку подтвержденную клиентом сводку и переходит к выбору даты
+func (b *Bot) confirmReview(ctx context.Context, callback *tgbotapi.CallbackQuery, session *models.UserSession, request *models.ServiceRequest) {
+	chatID := callback.Message.Chat.ID
+
+	// Запоминаем автомобиль для следующих заявок
+	b.rememberVehicle(ctx, request)
+
+	now := time.Now()
+	request.ApprovedAt = &now
+	request.ApprovedSummary = b.requestSummary(request)
+	request.Stage = models.StageDateSelection
+	if err := b.dbService.SaveServiceRequest(ctx, request); err != nil {
+		b.logger.Error("Ошибка сохранения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	session.Stage = models.StageDateSelection
+	b.dbService.SaveUserSession(ctx, session)
+
+	b.answerCallback(callback.ID, "Заявка подтверждена")
+	b.editMessage(chatID, callback.Message.MessageID, "✅ Заявка подтверждена:\n\n"+request.ApprovedSummary)
+	b.showAvailableDates(ctx, chatID)
+}
@@ -0,0 +1,491 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/services"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testUserID = 1001
+
+// fillQuestionnaire шаги от /start до экрана проверки заявки для нового клиента
+var fillQuestionnaire = []step{
+	{send: "/start", want: "Как вас зовут?"},
+	{send: "Иван", want: "Укажите номер телефона"},
+	{contact: "+79161234567", want: "Если знаете VIN"},
+	{press: "Пропустить", want: "Какая у вас модель Volvo?"},
+	{press: "XC60", want: "Укажите год выпуска"},
+	{send: "2018", want: "Выберите тип двигателя"},
+	{press: "Бензин", want: "Укажите объем двигателя"},
+	{send: "2.0", want: "Укажите пробег"},
+	{send: "85000", want: "Что именно вас беспокоит"},
+	{send: "Стучит подвеска спереди", want: "Когда впервые появилась проблема?"},
+	{press: "Недавно", want: "постоянно или периодически"},
+	{press: "Периодически", want: "Влияет ли это на движение"},
+	{send: "Нет", want: "попытки ремонта"},
+	{send: "Нет", want: "Меняли ли что-то недавно?"},
+	{send: "Нет", want: "Проверьте, пожалуйста, заявку"},
+}
+
+func TestScenarioHappyPath(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(1, "10:00", "11:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+
+	summary := c.reply("Проверьте, пожалуйста, заявку").Text
+	for _, line := range []string{"• Имя: Иван", "• Модель: XC60", "• Пробег: 85000", "• VIN: пропущено"} {
+		if !strings.Contains(summary, line) {
+			t.Errorf("в сводке нет %q:\n%s", line, summary)
+		}
+	}
+
+	c.run(
+		step{press: "✅ Подтвердить", wantAnswer: "Заявка подтверждена", want: "Выберите удобную дату"},
+		step{press: dateButton(date), want: "Выберите время для записи"},
+		step{press: "10:00", wantAnswer: "Заявка создана успешно!", want: "✅ Заявка успешно создана!"},
+	)
+
+	request := c.request()
+	if request.Status != models.StatusBooked {
+		t.Errorf("статус заявки %q, ожидался %q", request.Status, models.StatusBooked)
+	}
+	if request.Name != "Иван" || request.Phone != "+79161234567" || request.ModelID != "xc60" || request.YearNum != 2018 || request.MileageKm != 85000 {
+		t.Errorf("ответы сохранены неверно: %+v", request)
+	}
+	if request.ApprovedAt == nil || request.ApprovedSummary != summaryOf(summary) {
+		t.Errorf("подтверждение не записано: %v %q", request.ApprovedAt, request.ApprovedSummary)
+	}
+	if request.AvailableDateID != date.ID || request.TimeSlot != "10:00" || request.AppointmentDate.Hour() != 10 {
+		t.Errorf("время записи %v %s %v", request.AvailableDateID, request.TimeSlot, request.AppointmentDate)
+	}
+
+	if session := c.session(); session.Stage != models.StageCompleted {
+		t.Errorf("этап сессии %d, ожидался %d", session.Stage, models.StageCompleted)
+	}
+
+	booked, _ := env.store.GetAvailableDateByID(context.Background(), date.ID)
+	if !booked.TimeSlots[0].IsBooked || booked.TimeSlots[0].RequestID != request.ID || booked.TimeSlots[1].IsBooked {
+		t.Errorf("слоты после записи: %+v", booked.TimeSlots)
+	}
+
+	vehicles, _ := env.store.GetUserVehicles(context.Background(), testUserID)
+	if len(vehicles) != 1 || vehicles[0].ModelID != "xc60" {
+		t.Errorf("автомобиль не сохранен в гараж: %+v", vehicles)
+	}
+
+	// Сводка записи уходит сотрудникам
+	staff := false
+	for _, m := range env.history {
+		if m.ChatID == staffChatID && strings.Contains(m.Text, "🆕 Новая запись") {
+			staff = true
+		}
+	}
+	if !staff {
+		t.Error("сотрудники не получили уведомление о записи")
+	}
+}
+
+func TestScenarioCancelDuringQuestionnaire(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.client(testUserID)
+
+	c.run(
+		step{send: "/start", want: "Как вас зовут?"},
+		step{send: "Иван", want: "Укажите номер телефона"},
+	)
+	request := c.request()
+
+	c.run(step{send: "/cancel", want: "Заявка отменена"})
+
+	cancelled, err := env.store.GetServiceRequest(context.Background(), request.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != models.StatusCancelled {
+		t.Errorf("статус заявки %q, ожидался %q", cancelled.Status, models.StatusCancelled)
+	}
+
+	session := c.session()
+	if session.Stage != models.StageStart || !session.RequestID.IsZero() {
+		t.Errorf("сессия не сброшена: этап %d, заявка %s", session.Stage, session.RequestID.Hex())
+	}
+
+	// Следующее сообщение начинает новую заявку
+	c.run(step{send: "Привет", want: "Как вас зовут?"})
+	if c.request().ID == request.ID {
+		t.Error("новая заявка не создана")
+	}
+}
+
+func TestScenarioSaveKeepsStaffFields(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.client(testUserID)
+
+	c.run(
+		step{send: "/start", want: "Как вас зовут?"},
+		step{send: "Иван", want: "Укажите номер телефона"},
+	)
+
+	// Пока клиент заполняет анкету, сотрудник оставляет заметку
+	ctx := context.Background()
+	note := models.RequestNote{Author: "admin", Text: "Перезвонить"}
+	if err := env.store.AddServiceRequestNote(ctx, c.request().ID, note); err != nil {
+		t.Fatal(err)
+	}
+
+	c.run(step{contact: "+79161234567", want: "Если знаете VIN"})
+
+	request := c.request()
+	if request.Phone != "+79161234567" {
+		t.Errorf("телефон не сохранен: %q", request.Phone)
+	}
+	if len(request.Notes) != 1 || request.Notes[0].Text != note.Text {
+		t.Errorf("сохранение ответа стерло заметки: %+v", request.Notes)
+	}
+}
+
+func TestScenarioCancelBookedAppointment(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(2, "12:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+	c.run(
+		step{press: "✅ Подтвердить", want: "Выберите удобную дату"},
+		step{press: dateButton(date), want: "Выберите время"},
+		step{press: "12:00", want: "Заявка успешно создана"},
+		step{send: "/my", want: "XC60"},
+		step{press: "❌ Отменить", wantAnswer: "Запись отменена", want: "отменена"},
+		step{send: "/my", want: "У вас нет предстоящих записей"},
+	)
+
+	released, _ := env.store.GetAvailableDateByID(context.Background(), date.ID)
+	if released.TimeSlots[0].IsBooked {
+		t.Error("слот отмененной записи не освобожден")
+	}
+}
+
+func TestScenarioInvalidAnswers(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.client(testUserID)
+
+	c.run(
+		step{send: "/start", want: "Как вас зовут?"},
+		step{send: "И", want: "от 2 до 100 символов"},
+		step{send: "Иван", want: "Укажите номер телефона"},
+		step{send: "позвоните мне", want: "только цифры"},
+		step{send: "+79161234567", want: "Если знаете VIN"},
+		step{send: "123", want: "VIN"},
+		step{press: "Пропустить", want: "Какая у вас модель Volvo?"},
+		step{send: "Жигули", want: "Не удалось распознать модель"},
+		step{send: "xc60", want: "Укажите год выпуска"},
+		step{send: "1995", want: "не выпускалась в 1995 году"},
+		step{send: "2018", want: "Выберите тип двигателя"},
+		step{send: "Керосин", want: "выберите вариант из предложенных"},
+		step{press: "Электро", want: "Укажите пробег"},
+	)
+
+	request := c.request()
+	if _, answered := request.Answers["engine_volume"]; answered {
+		t.Error("для электромобиля задан вопрос об объеме двигателя")
+	}
+	if request.EngineType != "Электро" || request.ModelID != "xc60" {
+		t.Errorf("ответы сохранены неверно: %q %q", request.EngineType, request.ModelID)
+	}
+	if session := c.session(); session.QuestionID != "mileage" {
+		t.Errorf("текущий вопрос %q, ожидался mileage", session.QuestionID)
+	}
+}
+
+func TestScenarioBackAndEdit(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.client(testUserID)
+
+	c.run(
+		step{send: "/start", want: "Как вас зовут?"},
+		step{send: "Иван", want: "Укажите номер телефона"},
+		step{send: backButtonText, want: "Текущий ответ: Иван"},
+		step{send: "Петр", want: "Укажите номер телефона"},
+	)
+	if name := c.request().Name; name != "Петр" {
+		t.Fatalf("имя после исправления %q", name)
+	}
+
+	c.run(fillQuestionnaire[2:]...)
+	c.run(
+		step{press: "✏️ Изменить"},
+		step{press: "✏️ Пробег", want: "Текущий ответ: 85000"},
+		step{send: "90000", want: "Проверьте, пожалуйста, заявку"},
+	)
+
+	if !strings.Contains(c.reply("Проверьте").Text, "• Пробег: 90000") {
+		t.Errorf("исправленный ответ не попал в сводку:\n%s", c.dump())
+	}
+	if request := c.request(); request.MileageKm != 90000 || request.Stage != models.StageReview {
+		t.Errorf("пробег %d, этап %d", request.MileageKm, request.Stage)
+	}
+}
+
+func TestScenarioTimeSelectionErrors(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(1, "10:00")
+	c := env.client(testUserID)
+	other := env.client(testUserID + 1)
+
+	c.run(fillQuestionnaire...)
+
+	// Время нельзя выбрать, пока заявка не подтверждена
+	c.run(step{data: "time_" + date.ID.Hex() + "_10:00", wantAnswer: "Сначала подтвердите заявку", want: "Проверьте, пожалуйста, заявку"})
+	if c.request().Status != models.StatusDraft {
+		t.Fatal("неподтвержденная заявка записана на время")
+	}
+
+	c.run(
+		step{send: "10:00", want: "нажмите «Подтвердить» или «Изменить»"},
+		step{press: "✅ Подтвердить", want: "Выберите удобную дату"},
+		step{press: dateButton(date), want: "Выберите время"},
+		step{data: "time_broken", wantAnswer: "Неверный формат времени"},
+		step{data: "unknown", wantAnswer: "Неизвестный callback"},
+	)
+
+	// Пока клиент выбирал, время занял другой клиент
+	other.run(fillQuestionnaire...)
+	other.run(
+		step{press: "✅ Подтвердить"},
+		step{press: dateButton(date)},
+		step{press: "10:00", want: "Заявка успешно создана"},
+	)
+
+	c.run(step{press: "10:00", wantAnswer: "Это время уже занято", want: "только что заняли"})
+	if c.reply("на эту дату нет свободного времени") == nil {
+		t.Errorf("не показаны актуальные слоты:\n%s", c.dump())
+	}
+	if request := c.request(); request.Status != models.StatusDraft || !request.AvailableDateID.IsZero() {
+		t.Errorf("заявка записана на занятое время: %q %s", request.Status, request.TimeSlot)
+	}
+}
+
+func TestScenarioStaleButtons(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.client(testUserID)
+
+	c.run(
+		step{send: "/start", want: "Как вас зовут?"},
+		step{send: "Иван", want: "Укажите номер телефона"},
+		step{contact: "+79161234567", want: "Если знаете VIN"},
+		step{press: "Пропустить", want: "Какая у вас модель Volvo?"},
+		// Кнопка «Пропустить» под уже отвеченным вопросом
+		step{data: "qa:vin:", wantAnswer: "Этот вопрос уже неактуален"},
+		step{data: "review_confirm", wantAnswer: "Эта заявка уже неактуальна"},
+	)
+
+	if session := c.session(); session.QuestionID != "volvo_model" {
+		t.Errorf("текущий вопрос %q, ожидался volvo_model", session.QuestionID)
+	}
+}
+
+func TestScenarioRescheduleFromMy(t *testing.T) {
+	env := newTestEnv(t)
+	first := env.addDate(1, "10:00")
+	second := env.addDate(2, "11:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+	c.run(
+		step{press: "✅ Подтвердить"},
+		step{press: dateButton(first)},
+		step{press: "10:00", want: "Заявка успешно создана"},
+	)
+	booked := c.request()
+
+	// Клиент начал новую заявку, но передумал и переносит существующую запись
+	c.run(
+		step{send: "/start", want: "Продолжить как Иван"},
+		step{send: "/my", want: "XC60"},
+		step{press: "🔁 Перенести", want: "Незаконченная новая заявка отменена"},
+	)
+	if c.reply("Выберите удобную дату") == nil {
+		t.Fatalf("не показаны даты для переноса:\n%s", c.dump())
+	}
+	draft, _ := env.store.GetServiceRequests(context.Background(), services.RequestFilter{UserID: testUserID, Statuses: []string{models.StatusDraft}})
+	if len(draft) != 0 {
+		t.Errorf("черновик новой заявки не отменен: %d", len(draft))
+	}
+
+	c.run(
+		step{press: dateButton(second), want: "Выберите время"},
+		step{press: "11:00", wantAnswer: "Заявка создана успешно!", want: "✅ Запись перенесена!"},
+	)
+
+	request := c.request()
+	if request.ID != booked.ID || request.Status != models.StatusBooked || request.AvailableDateID != second.ID || request.TimeSlot != "11:00" {
+		t.Errorf("запись не перенесена: %s %q %s %s", request.ID.Hex(), request.Status, request.AvailableDateID.Hex(), request.TimeSlot)
+	}
+	if last := request.StatusHistory[len(request.StatusHistory)-1]; last.Reason != "перенос записи" {
+		t.Errorf("в истории статусов нет переноса: %+v", last)
+	}
+
+	released, _ := env.store.GetAvailableDateByID(context.Background(), first.ID)
+	if released.TimeSlots[0].IsBooked {
+		t.Error("прежний слот не освобожден")
+	}
+	taken, _ := env.store.GetAvailableDateByID(context.Background(), second.ID)
+	if !taken.TimeSlots[0].IsBooked || taken.TimeSlots[0].RequestID != request.ID {
+		t.Errorf("новый слот не забронирован: %+v", taken.TimeSlots[0])
+	}
+}
+
+func TestScenarioCancelDuringReschedule(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(1, "10:00", "11:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+	c.run(
+		step{press: "✅ Подтвердить"},
+		step{press: dateButton(date)},
+		step{press: "10:00", want: "Заявка успешно создана"},
+		// После записи /cancel не отменяет саму запись
+		step{send: "/cancel", want: "Ваша запись на"},
+		step{send: "/my", want: "XC60"},
+		step{press: "🔁 Перенести", want: "Выберите новую дату"},
+		// Клиент передумал переносить
+		step{send: "/cancel", want: "Перенос записи отменен"},
+		step{send: "/my", want: "XC60"},
+	)
+
+	requests, _ := env.store.GetServiceRequests(context.Background(), services.RequestFilter{UserID: testUserID})
+	if len(requests) != 1 || requests[0].Status != models.StatusBooked || requests[0].TimeSlot != "10:00" {
+		t.Fatalf("запись изменилась после /cancel: %+v", requests)
+	}
+	if session := c.session(); session.Stage != models.StageStart || !session.RequestID.IsZero() {
+		t.Errorf("сессия переноса не сброшена: этап %d", session.Stage)
+	}
+	slots, _ := env.store.GetAvailableDateByID(context.Background(), date.ID)
+	if !slots.TimeSlots[0].IsBooked {
+		t.Error("слот записи освобожден")
+	}
+}
+
+func TestScenarioOldTimeButtonAfterBooking(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(1, "10:00", "11:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+	c.run(
+		step{press: "✅ Подтвердить"},
+		step{press: dateButton(date)},
+		step{press: "10:00", want: "Заявка успешно создана"},
+	)
+
+	// Сообщение с кнопками времени заменено выбранным временем
+	edited := c.reply("Выбрано время")
+	if edited == nil || !edited.Edit || len(edited.Buttons()) != 0 {
+		t.Errorf("кнопки выбора времени не убраны: %+v", edited)
+	}
+
+	// Клиент нажимает кнопки, оставшиеся в старых сообщениях
+	c.run(
+		step{data: "time_" + date.ID.Hex() + "_11:00", wantAnswer: staleTimeSelectionText},
+		step{data: "date_" + date.ID.Hex(), wantAnswer: staleTimeSelectionText},
+	)
+
+	request := c.request()
+	if request.TimeSlot != "10:00" || request.Status != models.StatusBooked {
+		t.Errorf("старая кнопка изменила запись: %q %q", request.Status, request.TimeSlot)
+	}
+
+	// После сброса сессии у нее нет заявки, ответ тот же
+	c.run(
+		step{send: "/cancel", want: "остается в силе"},
+		step{data: "time_" + date.ID.Hex() + "_11:00", wantAnswer: staleTimeSelectionText},
+	)
+	slots, _ := env.store.GetAvailableDateByID(context.Background(), date.ID)
+	if !slots.TimeSlots[0].IsBooked || slots.TimeSlots[1].IsBooked {
+		t.Errorf("слоты после нажатия старой кнопки: %+v", slots.TimeSlots)
+	}
+}
+
+func TestScenarioAdminCancelDuringDateSelection(t *testing.T) {
+	env := newTestEnv(t)
+	date := env.addDate(1, "10:00")
+	c := env.client(testUserID)
+
+	c.run(fillQuestionnaire...)
+	c.run(
+		step{press: "✅ Подтвердить"},
+		step{press: dateButton(date), want: "Выберите время"},
+	)
+
+	// Пока клиент выбирает время, сотрудник отменяет заявку в админ-панели
+	if err := env.store.CancelServiceRequest(context.Background(), c.request(), "admin:manager", "дубль"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.run(step{press: "10:00", wantAnswer: "Эта заявка уже неактуальна. Нажмите /start для создания новой."})
+
+	if request := c.request(); request.Status != models.StatusCancelled {
+		t.Errorf("статус заявки %q, ожидался %q", request.Status, models.StatusCancelled)
+	}
+	slots, _ := env.store.GetAvailableDateByID(context.Background(), date.ID)
+	if slots.TimeSlots[0].IsBooked {
+		t.Error("слот забронирован за отмененной заявкой")
+	}
+	if c.reply("успешно") != nil {
+		t.Errorf("клиенту отправлено подтверждение записи:\n%s", c.dump())
+	}
+	for _, m := range env.history {
+		if m.ChatID == staffChatID {
+			t.Errorf("сотрудникам отправлена отмененная заявка: %s", m.Text)
+		}
+	}
+
+	// Новая заявка начинается с /start
+	c.run(step{send: "/start", want: "Добро пожаловать"})
+	if c.request().Status != models.StatusDraft {
+		t.Error("новая заявка не создана")
+	}
+}
+
+// summaryOf возвращает сводку заявки из сообщения проверки
+func summaryOf(text string) string {
+	const prefix, suffix = "Проверьте, пожалуйста, заявку:\n\n", "\n\nВсе верно?"
+	return text[len(prefix) : len(text)-len(suffix)]
+}
+
+func TestScenarioStaffGroupMessages(t *testing.T) {
+	env := newTestEnv(t)
+	staff := env.client(testUserID + 100)
+	group := &tgbotapi.Chat{ID: staffChatID, Type: "supergroup"}
+
+	// Сотрудник отвечает на сводку записи и нажимает чужую кнопку в группе
+	env.updateID++
+	staff.deliver(tgbotapi.Update{UpdateID: env.updateID, Message: &tgbotapi.Message{
+		MessageID: env.updateID,
+		From:      &tgbotapi.User{ID: staff.userID, FirstName: "Сотрудник"},
+		Chat:      group,
+		Text:      "Перезвоню клиенту после обеда",
+	}})
+	env.updateID++
+	staff.deliver(tgbotapi.Update{UpdateID: env.updateID, CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "group",
+		From:    &tgbotapi.User{ID: staff.userID},
+		Message: &tgbotapi.Message{MessageID: 1, Chat: group},
+		Data:    "review_confirm",
+	}})
+
+	for _, m := range env.history {
+		t.Errorf("бот ответил на сообщение в группе сотрудников: %d %q", m.ChatID, m.Text)
+	}
+	if session := staff.session(); session.Stage != models.StageStart || !session.RequestID.IsZero() {
+		t.Errorf("для сообщения из группы начата заявка: этап %d", session.Stage)
+	}
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"volvomaster/internal/models"
+	"volvomaster/internal/notify"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notifyStaff отправляет сводку по записи в чат сотрудников, если он настроен
+func (b *Bot) notifyStaff(request *models.ServiceRequest, rescheduled bool) {
+	if b.config.StaffChatID == 0 {
+		return
+	}
+
+	header := "🆕 Новая запись"
+	if rescheduled {
+		header = "🔁 Перенос записи"
+	}
+
+	text := fmt.Sprintf(`%s
+
+📅 %s
+👤 %s, %s
+🚗 Volvo %s %s, %s %s, пробег %s
+🔧 Проблема: %s
+🕒 Появилась: %s, %s
+⚠️ Влияние на безопасность: %s
+🛠 Ремонт ранее: %s
+🔄 Недавние изменения: %s`,
+		header,
+		request.AppointmentDate.Format("02.01.2006 в 15:04"),
+		request.Name, request.Contact,
+		request.VolvoModel, request.Year, request.EngineType, request.EngineVolume, request.Mileage,
+		request.Problem,
+		request.ProblemFirstAppeared, request.ProblemFrequency,
+		request.SafetyImpact,
+		request.PreviousRepairs,
+		request.RecentChanges)
+
+	if err := b.messenger.SendKeyboard(b.config.StaffChatID, text, staffKeyboard(request.ID, true)); err != nil {
+		b.logger.Error("Ошибка отправки уведомления сотрудникам: %v", err)
+	}
+}
+
+// staffKeyboard возвращает кнопки действий сотрудника по заявке
+func staffKeyboard(requestID primitive.ObjectID, withCallBack bool) tgbotapi.InlineKeyboardMarkup {
+	row := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", fmt.Sprintf("staff_confirm_%s", requestID.Hex())),
+	)
+	if withCallBack {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("📞 Перезвоню", fmt.Sprintf("staff_callback_%s", requestID.Hex())))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// handleStaffCallback обрабатывает кнопки под сводкой в чате сотрудников
+func (b *Bot) handleStaffCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Действия доступны только из настроенного чата сотрудников
+	if b.config.StaffChatID == 0 || callback.Message == nil || callback.Message.Chat.ID != b.config.StaffChatID {
+		b.answerCallback(callback.ID, "Действие доступно только сотрудникам")
+		return
+	}
+
+	var action, rawID string
+	if strings.HasPrefix(callback.Data, "staff_confirm_") {
+		action, rawID = "confirm", strings.TrimPrefix(callback.Data, "staff_confirm_")
+	} else if strings.HasPrefix(callback.Data, "staff_callback_") {
+		action, rawID = "callback", strings.TrimPrefix(callback.Data, "staff_callback_")
+	} else {
+		b.answerCallback(callback.ID, "Неизвестный callback")
+		return
+	}
+
+	requestID, err := primitive.ObjectIDFromHex(rawID)
+	if err != nil {
+		b.answerCallback(callback.ID, "Неверный формат заявки")
+		return
+	}
+
+	request, err := b.dbService.GetServiceRequest(ctx, requestID)
+	if err != nil {
+		b.logger.Error("Ошибка получения заявки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	if action == "confirm" {
+		b.handleStaffConfirm(ctx, callback, request)
+	} else {
+		b.handleStaffCallBack(ctx, callback, request)
+	}
+}
+
+// handleStaffConfirm подтверждает запись от имени сотрудника и сообщает об этом клиенту
+func (b *Bot) handleStaffConfirm(ctx context.Context, callback *tgbotapi.CallbackQuery, request *models.ServiceRequest) {
+	staff := staffName(callback.From)
+
+	if request.Status == models.StatusConfirmed {
+		b.answerCallback(callback.ID, "Запись уже подтверждена")
+		return
+	}
+
+	err := b.dbService.TransitionServiceRequest(ctx, request, models.StatusConfirmed, staffActor(callback.From), "подтверждено в чате сотрудников")
+	if err != nil {
+		b.logger.Error("Ошибка подтверждения заявки сотрудником: %v", err)
+		b.answerCallback(callback.ID, "Не удалось подтвердить заявку")
+		return
+	}
+
+	if text, err := notify.Render("confirmed", request); err == nil {
+		b.sendMessage(request.ChatID, text)
+	} else {
+		b.logger.Error("Ошибка подготовки уведомления клиенту: %v", err)
+	}
+
+	b.answerCallback(callback.ID, "Запись подтверждена")
+	b.editMessage(callback.Message.Chat.ID, callback.Message.MessageID,
+		callback.Message.Text+"\n\n✅ Подтвердил(а) "+staff)
+}
+
+// handleStaffCallBack фиксирует, что сотрудник взял на себя звонок клиенту
+func (b *Bot) handleStaffCallBack(ctx context.Context, callback *tgbotapi.CallbackQuery, request *models.ServiceRequest) {
+	staff := staffName(callback.From)
+
+	note := models.RequestNote{Author: staffActor(callback.From), Text: "Перезвонит клиенту"}
+	if err := b.dbService.AddServiceRequestNote(ctx, request.ID, note); err != nil {
+		b.logger.Error("Ошибка сохранения заметки: %v", err)
+		b.answerCallback(callback.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	b.answerCallback(callback.ID, "Отмечено")
+
+	// Оставляем кнопку подтверждения, если запись еще не подтверждена
+	text := callback.Message.Text + "\n\n📞 Перезвонит " + staff
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if request.Status == models.StatusBooked {
+		markup := staffKeyboard(request.ID, false)
+		keyboard = &markup
+	}
+	if err := b.messenger.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard); err != nil {
+		b.logger.Error("Ошибка редактирования сообщения: %v", err)
+	}
+}
+
+// staffName возвращает имя сотрудника для отображения в чате
+func staffName(user *tgbotapi.User) string {
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// staffActor возвращает автора изменений для истории заявки
+func staffActor(user *tgbotapi.User) string {
+	return fmt.Sprintf("staff:%d", user.ID)
+}
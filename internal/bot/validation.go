@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"volvomaster/internal/catalog"
+	"volvomaster/internal/models"
+	"volvomaster/internal/questionnaire"
+	"volvomaster/internal/validation"
+	"volvomaster/internal/vin"
+)
+
+// parseAnswerFormat разбирает ответ по формату вопроса, записывает типизированное значение в заявку
+// и возвращает ответ в том виде, в котором его нужно сохранить. Ошибка содержит текст для клиента.
+func (b *Bot) parseAnswerFormat(ctx context.Context, session *models.UserSession, request *models.ServiceRequest, format, answer string) (string, error) {
+	now := time.Now()
+
+	switch format {
+	case questionnaire.FormatVolvoModel:
+		return b.parseVolvoModel(ctx, session, request, answer)
+
+	case questionnaire.FormatVIN:
+		return b.parseVIN(ctx, session, request, answer, now)
+
+	case questionnaire.FormatYear:
+		year, err := validation.ParseYear(answer, now)
+		if err != nil {
+			return "", err
+		}
+
+		// Сверяем год с годами производства модели из каталога
+		if request.ModelID != "" {
+			model, err := b.dbService.GetVolvoModel(ctx, request.ModelID)
+			if err == nil && model.Generation(year) == nil {
+				return "", fmt.Errorf("Volvo %s не выпускалась в %d году (годы выпуска: %s). Проверьте год выпуска.",
+					model.Name, year, formatProductionYears(model))
+			}
+		}
+		request.YearNum = year
+
+	case questionnaire.FormatEngineVolume:
+		// Объем можно не указывать
+		if validation.IsUnknown(answer) {
+			request.EngineVolumeL = 0
+			return answer, nil
+		}
+		volume, err := validation.ParseEngineVolume(answer)
+		if err != nil {
+			return "", err
+		}
+		request.EngineVolumeL = volume
+
+	case questionnaire.FormatMileage:
+		mileage, err := validation.ParseMileage(answer)
+		if err != nil {
+			return "", err
+		}
+		request.MileageKm = mileage
+
+	case questionnaire.FormatContact:
+		phone, err := validation.ParseContact(answer)
+		if err != nil {
+			return "", err
+		}
+		request.Phone = phone
+	}
+
+	return answer, nil
+}
+
+// parseVolvoModel сопоставляет ответ с моделью каталога и сохраняет ее ID в заявке.
+// Модель не из каталога принимается, только если клиент выбрал «Другая модель».
+func (b *Bot) parseVolvoModel(ctx context.Context, session *models.UserSession, request *models.ServiceRequest, answer string) (string, error) {
+	catalogModels, err := b.dbService.GetVolvoModels(ctx)
+	if err != nil {
+		b.logger.Error("Ошибка получения каталога моделей: %v", err)
+	}
+
+	if model := catalog.Match(catalogModels, answer); model != nil {
+		delete(session.Data, otherModelKey)
+		request.ModelID = model.ID
+		return model.Name, nil
+	}
+
+	other, _ := session.Data[otherModelKey].(bool)
+	if !other && len(catalogModels) > 0 {
+		return "", errors.New("Не удалось распознать модель. Выберите ее из списка выше или нажмите «Другая модель».")
+	}
+
+	delete(session.Data, otherModelKey)
+	request.ModelID = ""
+	return answer, nil
+}
+
+// parseVIN проверяет VIN и, если это Volvo, заполняет по нему модель, год и двигатель
+func (b *Bot) parseVIN(ctx context.Context, session *models.UserSession, request *models.ServiceRequest, answer string, now time.Time) (string, error) {
+	number := vin.Normalize(answer)
+	if err := vin.Validate(number); err != nil {
+		return "", err
+	}
+	if !vin.IsVolvo(number) {
+		return number, nil
+	}
+
+	info := vin.Decode(number, now)
+	var decoded []string
+
+	var model *models.VolvoModel
+	if info.ModelID != "" {
+		var err error
+		if model, err = b.dbService.GetVolvoModel(ctx, info.ModelID); err != nil {
+			b.logger.Error("Ошибка получения модели %s: %v", info.ModelID, err)
+			model = nil
+		}
+	}
+
+	if model != nil {
+		request.ModelID = model.ID
+		b.prefillField(request, "volvo_model", model.Name)
+		decoded = append(decoded, "Volvo "+model.Name)
+	}
+
+	if info.Year != 0 {
+		request.YearNum = info.Year
+		b.prefillField(request, "year", strconv.Itoa(info.Year))
+		decoded = append(decoded, fmt.Sprintf("%d модельный год", info.Year))
+	}
+
+	// Двигатель заполняем, только если у поколения модели он единственный
+	if model != nil && info.Year != 0 {
+		if generation := model.Generation(info.Year); generation != nil {
+			if len(generation.EngineTypes) == 1 {
+				b.prefillField(request, "engine_type", generation.EngineTypes[0])
+				decoded = append(decoded, generation.EngineTypes[0])
+			}
+			if len(generation.EngineVolumes) == 1 {
+				request.EngineVolumeL = generation.EngineVolumes[0]
+				volume := strconv.FormatFloat(generation.EngineVolumes[0], 'f', 1, 64)
+				b.prefillField(request, "engine_volume", volume)
+				decoded = append(decoded, volume+" л")
+			}
+		}
+	}
+
+	if info.Plant != "" {
+		decoded = append(decoded, "завод: "+info.Plant)
+	}
+
+	if len(decoded) > 0 {
+		b.sendMessage(session.ChatID, "🔎 По VIN определено: "+strings.Join(decoded, ", ")+".")
+	}
+	return number, nil
+}
@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// webhookSecretHeader заголовок, в котором Telegram присылает секрет вебхука
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxWebhookBody максимальный размер тела запроса с обновлением
+const maxWebhookBody = 1 << 20
+
+// newWebhookServer создает HTTP-сервер, принимающий обновления по пути из WEBHOOK_URL
+func (b *Bot) newWebhookServer() (*http.Server, error) {
+	link, err := url.Parse(b.config.Webhook.URL)
+	if err != nil || link.Scheme != "https" || link.Host == "" {
+		return nil, fmt.Errorf("WEBHOOK_URL должен быть адресом https: %s", b.config.Webhook.URL)
+	}
+
+	path := link.Path
+	if path == "" {
+		path = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, b.webhookHandler())
+
+	return &http.Server{
+		Addr:              b.config.Webhook.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
+
+// webhookHandler проверяет секрет запроса и передает обновление диспетчеру
+func (b *Bot) webhookHandler() http.Handler {
+	secret := []byte(b.config.Webhook.SecretToken)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), secret) != 1 {
+			b.logger.Error("Запрос к вебхуку с неверным секретом от %s", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBody)).Decode(&update); err != nil {
+			b.logger.Error("Ошибка разбора обновления из вебхука: %v", err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		// Бот останавливается: Telegram повторит обновление позже
+		if !b.updates.Dispatch(update) {
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// serveWebhook занимает адрес сервера, регистрирует вебхук в Telegram и принимает обновления до остановки бота.
+// Вебхук регистрируется только после того, как адрес занят и сертификат загружен,
+// иначе Telegram отправлял бы обновления на неработающий адрес.
+func (b *Bot) serveWebhook() error {
+	if b.config.Webhook.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(b.config.Webhook.CertFile, b.config.Webhook.KeyFile)
+		if err != nil {
+			return fmt.Errorf("ошибка загрузки сертификата вебхука: %w", err)
+		}
+		b.webhook.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	listener, err := net.Listen("tcp", b.webhook.Addr)
+	if err != nil {
+		return fmt.Errorf("ошибка запуска сервера вебхука: %w", err)
+	}
+
+	if err := b.setWebhook(); err != nil {
+		listener.Close()
+		return fmt.Errorf("ошибка установки вебхука: %w", err)
+	}
+	b.logger.Info("Вебхук %s установлен, обновления принимаются на %s", b.config.Webhook.URL, listener.Addr())
+
+	if b.webhook.TLSConfig != nil {
+		err = b.webhook.ServeTLS(listener, "", "")
+	} else {
+		err = b.webhook.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("ошибка сервера вебхука: %w", err)
+	}
+	return nil
+}
+
+// stopWebhook удаляет вебхук (если настроено) и дожидается завершения начатых запросов, но не дольше ctx
+func (b *Bot) stopWebhook(ctx context.Context) {
+	// Обновления, пришедшие после удаления вебхука, Telegram сохранит до следующего запуска
+	if b.config.Webhook.DeleteOnStop {
+		if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			b.logger.Error("Ошибка удаления вебхука: %v", err)
+		}
+	}
+
+	if err := b.webhook.Shutdown(ctx); err != nil {
+		b.logger.Error("Ошибка остановки сервера вебхука: %v", err)
+	}
+}
+
+// setWebhook регистрирует вебхук с секретом. В tgbotapi v5.5.1 у WebhookConfig нет поля secret_token,
+// поэтому запрос собирается вручную.
+func (b *Bot) setWebhook() error {
+	params := tgbotapi.Params{
+		"url":          b.config.Webhook.URL,
+		"secret_token": b.config.Webhook.SecretToken,
+	}
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query"}); err != nil {
+		return err
+	}
+
+	_, err := b.api.MakeRequest("setWebhook", params)
+	return err
+}
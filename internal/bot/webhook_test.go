@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	env := newTestEnv(t)
+	env.bot.config.Webhook.SecretToken = "secret"
+	handler := env.bot.webhookHandler()
+
+	update := `{"update_id": 1, "message": {"message_id": 1, "date": 0, "text": "/start",
+		"from": {"id": 1001, "first_name": "Тест"}, "chat": {"id": 1001, "type": "private"},
+		"entities": [{"type": "bot_command", "offset": 0, "length": 6}]}}`
+
+	serve := func(method, secret, body string) int {
+		r := httptest.NewRequest(method, "/telegram", strings.NewReader(body))
+		if secret != "" {
+			r.Header.Set(webhookSecretHeader, secret)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		secret string
+		body   string
+		want   int
+	}{
+		{"без секрета", http.MethodPost, "", update, http.StatusUnauthorized},
+		{"неверный секрет", http.MethodPost, "wrong", update, http.StatusUnauthorized},
+		{"не POST", http.MethodGet, "secret", "", http.StatusMethodNotAllowed},
+		{"не JSON", http.MethodPost, "secret", "{", http.StatusBadRequest},
+		{"обновление", http.MethodPost, "secret", update, http.StatusOK},
+	}
+	for _, c := range cases {
+		if code := serve(c.method, c.secret, c.body); code != c.want {
+			t.Errorf("%s: код %d, ожидался %d", c.name, code, c.want)
+		}
+	}
+
+	// Дожидаемся обработки принятого обновления
+	env.bot.updates.Shutdown(context.Background())
+
+	replies := 0
+	for _, m := range env.fake.Take() {
+		if m.ChatID == 1001 && strings.Contains(m.Text, "Как вас зовут?") {
+			replies++
+		}
+	}
+	if replies != 1 {
+		t.Errorf("бот ответил %d раз, ожидался один ответ на единственное принятое обновление", replies)
+	}
+
+	// После остановки Telegram должен повторить обновление позже
+	if code := serve(http.MethodPost, "secret", update); code != http.StatusServiceUnavailable {
+		t.Errorf("после остановки код %d, ожидался %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestServeWebhookAddressInUse(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	env := newTestEnv(t)
+	env.bot.webhook = &http.Server{Addr: busy.Addr().String(), Handler: env.bot.webhookHandler()}
+
+	// У тестового бота нет API Telegram: если бы вебхук регистрировался до занятия адреса, тест упал бы с паникой
+	if err := env.bot.serveWebhook(); err == nil {
+		t.Fatal("сервер вебхука запустился на занятом адресе")
+	}
+}
@@ -0,0 +1,176 @@
+// Package catalog содержит каталог моделей Volvo и сопоставление
+// введенного клиентом текста с моделью из каталога.
+package catalog
+
+import (
+	"strings"
+
+	"volvomaster/internal/models"
+)
+
+var (
+	petrol   = "Бензин"
+	diesel   = "Дизель"
+	hybrid   = "Гибрид"
+	electric = "Электро"
+)
+
+// DefaultModels каталог моделей, которым заполняется коллекция volvo_models
+var DefaultModels = []models.VolvoModel{
+	{ID: "240", Name: "240", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1974, YearTo: 1993, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.1, 2.3, 2.4}},
+	}},
+	{ID: "740", Name: "740", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1984, YearTo: 1992, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.3, 2.4}},
+	}},
+	{ID: "850", Name: "850", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1991, YearTo: 1996, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.3, 2.4, 2.5}},
+	}},
+	{ID: "940", Name: "940", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1990, YearTo: 1998, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.3, 2.4}},
+	}},
+	{ID: "c30", Name: "C30", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2006, YearTo: 2013, EngineTypes: []string{petrol, diesel, electric}, EngineVolumes: []float64{1.6, 2.0, 2.4, 2.5}},
+	}},
+	{ID: "c40", Name: "C40", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2021, EngineTypes: []string{electric}},
+	}},
+	{ID: "c70", Name: "C70", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1996, YearTo: 2005, EngineTypes: []string{petrol}, EngineVolumes: []float64{2.0, 2.3, 2.4}},
+		{Name: "II", YearFrom: 2006, YearTo: 2013, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 2.5}},
+	}},
+	{ID: "ex30", Name: "EX30", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2023, EngineTypes: []string{electric}},
+	}},
+	{ID: "ex90", Name: "EX90", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2024, EngineTypes: []string{electric}},
+	}},
+	{ID: "s40", Name: "S40", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1995, YearTo: 2004, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{1.6, 1.8, 1.9, 2.0}},
+		{Name: "II", YearFrom: 2004, YearTo: 2012, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{1.6, 1.8, 2.0, 2.4, 2.5}},
+	}},
+	{ID: "s60", Name: "S60", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2000, YearTo: 2009, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 2.5}},
+		{Name: "II", YearFrom: 2010, YearTo: 2018, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{1.6, 2.0, 2.4, 2.5, 3.0}},
+		{Name: "III", YearFrom: 2018, EngineTypes: []string{petrol, hybrid}, EngineVolumes: []float64{2.0}},
+	}},
+	{ID: "s70", Name: "S70", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1996, YearTo: 2000, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.3, 2.4, 2.5}},
+	}},
+	{ID: "s80", Name: "S80", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1998, YearTo: 2006, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 2.5, 2.8, 2.9}},
+		{Name: "II", YearFrom: 2006, YearTo: 2016, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 2.5, 3.0, 3.2, 4.4}},
+	}},
+	{ID: "s90", Name: "S90", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1996, YearTo: 1998, EngineTypes: []string{petrol}, EngineVolumes: []float64{2.9}},
+		{Name: "II", YearFrom: 2016, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{2.0}},
+	}},
+	{ID: "v40", Name: "V40", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1995, YearTo: 2004, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{1.6, 1.8, 1.9, 2.0}},
+		{Name: "II", YearFrom: 2012, YearTo: 2019, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{1.5, 1.6, 2.0, 2.5}},
+	}},
+	{ID: "v50", Name: "V50", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2004, YearTo: 2012, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{1.6, 1.8, 2.0, 2.4, 2.5}},
+	}},
+	{ID: "v60", Name: "V60", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2010, YearTo: 2018, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{1.6, 2.0, 2.4, 2.5, 3.0}},
+		{Name: "II", YearFrom: 2018, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{2.0}},
+	}},
+	{ID: "v70", Name: "V70", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1996, YearTo: 2000, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.3, 2.4, 2.5}},
+		{Name: "II", YearFrom: 2000, YearTo: 2007, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 2.5}},
+		{Name: "III", YearFrom: 2007, YearTo: 2016, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{1.6, 2.0, 2.4, 2.5, 3.0, 3.2}},
+	}},
+	{ID: "v90", Name: "V90", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1996, YearTo: 1998, EngineTypes: []string{petrol}, EngineVolumes: []float64{2.9}},
+		{Name: "II", YearFrom: 2016, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{2.0}},
+	}},
+	{ID: "xc40", Name: "XC40", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2017, EngineTypes: []string{petrol, diesel, hybrid, electric}, EngineVolumes: []float64{1.5, 2.0}},
+	}},
+	{ID: "xc60", Name: "XC60", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2008, YearTo: 2017, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 2.5, 3.0, 3.2}},
+		{Name: "II", YearFrom: 2017, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{2.0}},
+	}},
+	{ID: "xc70", Name: "XC70", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 1997, YearTo: 2007, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.4, 2.5}},
+		{Name: "II", YearFrom: 2007, YearTo: 2016, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.0, 2.4, 3.0, 3.2}},
+	}},
+	{ID: "xc90", Name: "XC90", Generations: []models.ModelGeneration{
+		{Name: "I", YearFrom: 2002, YearTo: 2014, EngineTypes: []string{petrol, diesel}, EngineVolumes: []float64{2.4, 2.5, 2.9, 3.2, 4.4}},
+		{Name: "II", YearFrom: 2014, EngineTypes: []string{petrol, diesel, hybrid}, EngineVolumes: []float64{2.0}},
+	}},
+}
+
+// lookalikes кириллические буквы, которые пишут вместо латинских ("ХС90")
+var lookalikes = strings.NewReplacer(
+	"А", "A", "В", "V", "С", "C", "Е", "E", "Х", "X",
+	"К", "K", "М", "M", "Н", "H", "О", "O", "Р", "P", "Т", "T",
+)
+
+// Normalize приводит название модели к виду "XC90": латиница в верхнем регистре
+// без марки, пробелов и дефисов
+func Normalize(text string) string {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	for _, brand := range []string{"VOLVO", "ВОЛЬВО", "ВОЛЬВА"} {
+		text = strings.TrimPrefix(text, brand)
+	}
+	text = lookalikes.Replace(text)
+
+	var b strings.Builder
+	for _, r := range text {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Match находит модель каталога по введенному тексту.
+// Допускается одна опечатка, если она однозначно указывает на модель.
+func Match(catalog []models.VolvoModel, text string) *models.VolvoModel {
+	name := Normalize(text)
+	if name == "" {
+		return nil
+	}
+
+	var closest *models.VolvoModel
+	ambiguous := false
+	for i := range catalog {
+		candidate := Normalize(catalog[i].Name)
+		if candidate == name {
+			return &catalog[i]
+		}
+		if len(name) >= 3 && distance(candidate, name) == 1 {
+			ambiguous = closest != nil
+			closest = &catalog[i]
+		}
+	}
+
+	if ambiguous {
+		return nil
+	}
+	return closest
+}
+
+// distance расстояние Левенштейна между строками из ASCII-символов
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
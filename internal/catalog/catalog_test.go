@@ -0,0 +1,54 @@
+package catalog
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // ID модели, пусто - модель не найдена
+	}{
+		{"XC90", "xc90"},
+		{"xc 90", "xc90"},
+		{"Volvo XC-60", "xc60"},
+		{"вольво хс90", "xc90"},
+		{"ХС60", "xc60"},
+		{"s60", "s60"},
+		{"740", "740"},
+		{"XC9O", "xc90"}, // одна опечатка
+		{"XC6", "xc60"},  // пропущена цифра
+		{"XC50", ""},     // на одну букву отличается от XC40, XC60, XC70 и XC90
+		{"S6", ""},       // слишком коротко для поиска с опечаткой
+		{"XC100", ""},
+		{"Volvo", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Match(DefaultModels, tt.text)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("Match(%q) = %s, ожидалось nil", tt.text, got.ID)
+				}
+				return
+			}
+			if got == nil || got.ID != tt.want {
+				t.Errorf("Match(%q) = %v, ожидалось %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Volvo XC-90":  "XC90",
+		" вольво хс90": "XC90",
+		"ВОЛЬВА С40":   "C40",
+		"v 70":         "V70",
+	}
+	for text, want := range tests {
+		if got := Normalize(text); got != want {
+			t.Errorf("Normalize(%q) = %q, ожидалось %q", text, got, want)
+		}
+	}
+}
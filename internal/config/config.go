@@ -1,10 +1,66 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
-	TelegramToken string
-	MongoURI      string
+	TelegramToken    string
+	DatabaseBackend  string // "mongo" или "memory" (данные в памяти, теряются при перезапуске)
+	MongoURI         string
+	Timezone         string
+	AdminTelegramIDs []int64
+	AdminURL         string
+	StaffChatID      int64
+
+	// Получение обновлений от Telegram: "polling" (по умолчанию) или "webhook".
+	// В режиме polling работает один экземпляр: Telegram не отдает обновления нескольким получателям сразу.
+	// В режиме webhook экземпляров может быть несколько, обновления клиента они обрабатывают по очереди под его блокировкой в базе.
+	UpdatesMode string
+	Webhook     WebhookConfig
+
+	// Сколько обновлений Telegram обрабатывается одновременно (обновления одного пользователя - всегда по очереди)
+	MaxConcurrentUpdates int
+
+	// Сколько при остановке ждать обработки уже принятых обновлений, прежде чем прервать обработчики
+	ShutdownTimeout time.Duration
+
+	// Анкета: JSON-файл или ID документа в коллекции questionnaires.
+	// Если ничего не задано, используется встроенная анкета.
+	QuestionnaireFile string
+	QuestionnaireID   string
+
+	Storage StorageConfig
+
+	// Незаконченные заявки: после SessionNudgeAfter бездействия клиенту предлагается продолжить,
+	// после SessionExpireAfter сессия сбрасывается, а черновик заявки отменяется. 0 - отключено.
+	SessionNudgeAfter  time.Duration
+	SessionExpireAfter time.Duration
+}
+
+// WebhookConfig настройки приема обновлений через вебхук
+type WebhookConfig struct {
+	URL          string // публичный адрес вебхука, например https://bot.example.com/telegram
+	Listen       string // адрес HTTP-сервера бота
+	SecretToken  string // секрет, который Telegram присылает в заголовке каждого запроса
+	CertFile     string // сертификат и ключ TLS; если не заданы, сервер работает по HTTP за обратным прокси
+	KeyFile      string
+	DeleteOnStop bool // удалять вебхук при остановке; по умолчанию нет, иначе остановка одного экземпляра отключила бы вебхук остальным
+}
+
+// StorageConfig настройки хранилища вложений. Пустой Backend - файлы не скачиваются,
+// в заявке хранятся только file ID Telegram.
+type StorageConfig struct {
+	Backend     string // "", "local" или "s3"
+	Dir         string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
 }
 
 func Load() *Config {
@@ -13,15 +69,138 @@ func Load() *Config {
 		panic("TELEGRAM_BOT_TOKEN не установлен в переменных окружения")
 	}
 
+	updatesMode := getEnv("UPDATES_MODE", "polling")
+	webhook := WebhookConfig{
+		URL:          getEnv("WEBHOOK_URL", ""),
+		Listen:       getEnv("WEBHOOK_LISTEN", ":8443"),
+		SecretToken:  getEnv("WEBHOOK_SECRET_TOKEN", ""),
+		CertFile:     getEnv("WEBHOOK_CERT_FILE", ""),
+		KeyFile:      getEnv("WEBHOOK_KEY_FILE", ""),
+		DeleteOnStop: getEnvBool("WEBHOOK_DELETE_ON_STOP", false),
+	}
+
+	switch updatesMode {
+	case "polling":
+	case "webhook":
+		if webhook.URL == "" || webhook.SecretToken == "" {
+			panic("для UPDATES_MODE=webhook нужны WEBHOOK_URL и WEBHOOK_SECRET_TOKEN")
+		}
+		if (webhook.CertFile == "") != (webhook.KeyFile == "") {
+			panic("WEBHOOK_CERT_FILE и WEBHOOK_KEY_FILE задаются вместе")
+		}
+	default:
+		panic("UPDATES_MODE содержит неверное значение: " + updatesMode)
+	}
+
 	return &Config{
-		TelegramToken: token,
-		MongoURI:      getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		TelegramToken:    token,
+		DatabaseBackend:  getEnv("DATABASE_BACKEND", "mongo"),
+		MongoURI:         getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		Timezone:         getEnv("TIMEZONE", "Europe/Moscow"),
+		AdminTelegramIDs: getEnvInt64List("ADMIN_TELEGRAM_IDS"),
+		AdminURL:         getEnv("ADMIN_URL", "http://localhost:8080"),
+		StaffChatID:      getEnvInt64("STAFF_CHAT_ID", 0),
+
+		UpdatesMode: updatesMode,
+		Webhook:     webhook,
+
+		MaxConcurrentUpdates: int(getEnvInt64("MAX_CONCURRENT_UPDATES", 16)),
+		ShutdownTimeout:      getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		QuestionnaireFile: getEnv("QUESTIONNAIRE_FILE", ""),
+		QuestionnaireID:   getEnv("QUESTIONNAIRE_ID", ""),
+
+		Storage: LoadStorage(),
+
+		SessionNudgeAfter:  getEnvDuration("SESSION_NUDGE_AFTER", 2*time.Hour),
+		SessionExpireAfter: getEnvDuration("SESSION_EXPIRE_AFTER", 72*time.Hour),
+	}
+}
+
+// LoadStorage читает настройки хранилища вложений (используется и ботом, и админ-панелью)
+func LoadStorage() StorageConfig {
+	return StorageConfig{
+		Backend:     getEnv("ATTACHMENTS_STORAGE", ""),
+		Dir:         getEnv("ATTACHMENTS_DIR", "attachments"),
+		S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+		S3Bucket:    getEnv("S3_BUCKET", ""),
+		S3Region:    getEnv("S3_REGION", ""),
+		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
 	}
 }
 
+// IsAdminTelegramID проверяет, входит ли пользователь Telegram в список сотрудников
+func (c *Config) IsAdminTelegramID(userID int64) bool {
+	for _, id := range c.AdminTelegramIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		panic(key + " содержит неверное значение: " + value)
+	}
+	return parsed
+}
+
+// getEnvBool читает логическое значение: true/false, 1/0
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		panic(key + " содержит неверное значение: " + value)
+	}
+	return parsed
+}
+
+// getEnvDuration читает длительность в формате Go: "30m", "2h", "72h"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || parsed < 0 {
+		panic(key + " содержит неверное значение: " + value)
+	}
+	return parsed
+}
+
+// getEnvInt64List читает список чисел, разделенных запятыми
+func getEnvInt64List(key string) []int64 {
+	var values []int64
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			panic(key + " содержит неверное значение: " + part)
+		}
+		values = append(values, value)
+	}
+	return values
+}
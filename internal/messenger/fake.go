@@ -0,0 +1,168 @@
+package messenger
+
+import (
+	"errors"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Message сообщение, отправленное или измененное через Fake
+type Message struct {
+	ChatID    int64
+	MessageID int    // ID, присвоенный отправленному сообщению, или ID измененного сообщения
+	Text      string // при изменении только кнопок пустой
+	Keyboard  interface{}
+	Edit      bool // сообщение не отправлено, а изменено
+}
+
+// Buttons возвращает inline-кнопки сообщения одним списком
+func (m Message) Buttons() []tgbotapi.InlineKeyboardButton {
+	var markup *tgbotapi.InlineKeyboardMarkup
+	switch keyboard := m.Keyboard.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		markup = &keyboard
+	case *tgbotapi.InlineKeyboardMarkup:
+		markup = keyboard
+	}
+	if markup == nil {
+		return nil
+	}
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, row := range markup.InlineKeyboard {
+		buttons = append(buttons, row...)
+	}
+	return buttons
+}
+
+// Button возвращает callback data inline-кнопки с указанным текстом
+func (m Message) Button(text string) (string, bool) {
+	for _, button := range m.Buttons() {
+		if button.Text == text && button.CallbackData != nil {
+			return *button.CallbackData, true
+		}
+	}
+	return "", false
+}
+
+// CallbackAnswer ответ на нажатие кнопки
+type CallbackAnswer struct {
+	CallbackID string
+	Text       string
+}
+
+// Fake запоминает сообщения вместо отправки. Безопасен для использования из нескольких горутин.
+type Fake struct {
+	mu       sync.Mutex
+	messages []Message
+	answers  []CallbackAnswer
+	files    map[string]string
+	lastID   int
+	err      error
+}
+
+func NewFake() *Fake {
+	return &Fake{files: make(map[string]string)}
+}
+
+// Fail заставляет методы отправки возвращать err; nil возвращает обычное поведение
+func (f *Fake) Fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// AddFile задает ссылку, которую FileURL вернет для file ID
+func (f *Fake) AddFile(fileID, url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[fileID] = url
+}
+
+// Messages возвращает все отправленные и измененные сообщения по порядку
+func (f *Fake) Messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.messages...)
+}
+
+// Take возвращает сообщения, накопленные после прошлого вызова Take, и забывает их
+func (f *Fake) Take() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	messages := f.messages
+	f.messages = nil
+	return messages
+}
+
+// CallbackAnswers возвращает ответы на нажатия кнопок по порядку
+func (f *Fake) CallbackAnswers() []CallbackAnswer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]CallbackAnswer(nil), f.answers...)
+}
+
+func (f *Fake) SendText(chatID int64, text string) error {
+	return f.SendKeyboard(chatID, text, nil)
+}
+
+func (f *Fake) SendKeyboard(chatID int64, text string, keyboard interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+
+	f.lastID++
+	f.messages = append(f.messages, Message{ChatID: chatID, MessageID: f.lastID, Text: text, Keyboard: keyboard})
+	return nil
+}
+
+func (f *Fake) EditMessage(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+
+	message := Message{ChatID: chatID, MessageID: messageID, Text: text, Edit: true}
+	if keyboard != nil {
+		message.Keyboard = *keyboard
+	}
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func (f *Fake) EditKeyboard(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+
+	f.messages = append(f.messages, Message{ChatID: chatID, MessageID: messageID, Keyboard: keyboard, Edit: true})
+	return nil
+}
+
+func (f *Fake) AnswerCallback(callbackID, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+
+	f.answers = append(f.answers, CallbackAnswer{CallbackID: callbackID, Text: text})
+	return nil
+}
+
+func (f *Fake) FileURL(fileID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	url, ok := f.files[fileID]
+	if !ok {
+		return "", errors.New("файл не найден")
+	}
+	return url, nil
+}
@@ -0,0 +1,29 @@
+// Package messenger отделяет бота от Telegram Bot API: бот отправляет сообщения через интерфейс Messenger,
+// поэтому разговор с клиентом можно провести в тестах без сети (см. Fake).
+package messenger
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger отправляет сообщения в чаты и отвечает на нажатия кнопок
+type Messenger interface {
+	// SendText отправляет текстовое сообщение
+	SendText(chatID int64, text string) error
+
+	// SendKeyboard отправляет сообщение с клавиатурой: tgbotapi.InlineKeyboardMarkup,
+	// tgbotapi.ReplyKeyboardMarkup или tgbotapi.ReplyKeyboardRemove
+	SendKeyboard(chatID int64, text string, keyboard interface{}) error
+
+	// EditMessage заменяет текст сообщения; если keyboard равна nil, кнопки под сообщением убираются
+	EditMessage(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error
+
+	// EditKeyboard заменяет только кнопки под сообщением
+	EditKeyboard(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) error
+
+	// AnswerCallback отвечает на нажатие кнопки, непустой text показывается всплывающим уведомлением
+	AnswerCallback(callbackID, text string) error
+
+	// FileURL возвращает ссылку для скачивания файла по file ID
+	FileURL(fileID string) (string, error)
+}
@@ -0,0 +1,51 @@
+package messenger
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram отправляет сообщения через Telegram Bot API
+type Telegram struct {
+	api *tgbotapi.BotAPI
+}
+
+func NewTelegram(api *tgbotapi.BotAPI) *Telegram {
+	return &Telegram{api: api}
+}
+
+func (t *Telegram) SendText(chatID int64, text string) error {
+	_, err := t.api.Send(tgbotapi.NewMessage(chatID, text))
+	return err
+}
+
+func (t *Telegram) SendKeyboard(chatID int64, text string, keyboard interface{}) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+
+	_, err := t.api.Send(msg)
+	return err
+}
+
+func (t *Telegram) EditMessage(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = keyboard
+
+	_, err := t.api.Send(edit)
+	return err
+}
+
+func (t *Telegram) EditKeyboard(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	_, err := t.api.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard))
+	return err
+}
+
+func (t *Telegram) AnswerCallback(callbackID, text string) error {
+	_, err := t.api.Request(tgbotapi.NewCallback(callbackID, text))
+	return err
+}
+
+// FileURL возвращает ссылку на файл из Telegram по file ID.
+// Ссылка содержит токен бота, поэтому ее нельзя показывать пользователям.
+func (t *Telegram) FileURL(fileID string) (string, error) {
+	return t.api.GetFileDirectURL(fileID)
+}
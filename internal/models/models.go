@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,12 +15,29 @@ type User struct {
 	Username  string             `bson:"username,omitempty" json:"username,omitempty"`
 	FirstName string             `bson:"first_name,omitempty" json:"first_name,omitempty"`
 	LastName  string             `bson:"last_name,omitempty" json:"last_name,omitempty"`
+	Name      string             `bson:"name,omitempty" json:"name,omitempty"` // имя, указанное в заявке
 	Phone     string             `bson:"phone,omitempty" json:"phone,omitempty"`
 	Telegram  string             `bson:"telegram,omitempty" json:"telegram,omitempty"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// DisplayName возвращает имя для обращения к пользователю
+func (u *User) DisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// Contact возвращает сохраненный контакт для связи: телефон или Telegram
+func (u *User) Contact() string {
+	if u.Phone != "" {
+		return u.Phone
+	}
+	return u.Telegram
+}
+
 // ServiceRequest представляет заявку на обслуживание
 type ServiceRequest struct {
 	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -29,13 +47,22 @@ type ServiceRequest struct {
 	// Первый этап - контактная информация
 	Name    string `bson:"name" json:"name"`
 	Contact string `bson:"contact" json:"contact"`
+	Phone   string `bson:"phone,omitempty" json:"phone,omitempty"` // телефон в формате E.164
 
 	// Второй этап - информация об автомобиле
-	VolvoModel   string `bson:"volvo_model" json:"volvo_model"`
-	Year         string `bson:"year" json:"year"`
-	EngineType   string `bson:"engine_type" json:"engine_type"`
-	EngineVolume string `bson:"engine_volume" json:"engine_volume"`
-	Mileage      string `bson:"mileage" json:"mileage"`
+	VIN          string             `bson:"vin,omitempty" json:"vin,omitempty"`
+	VolvoModel   string             `bson:"volvo_model" json:"volvo_model"`
+	ModelID      string             `bson:"model_id,omitempty" json:"model_id,omitempty"`     // ID модели в каталоге volvo_models
+	VehicleID    primitive.ObjectID `bson:"vehicle_id,omitempty" json:"vehicle_id,omitempty"` // автомобиль из гаража клиента
+	Year         string             `bson:"year" json:"year"`
+	EngineType   string             `bson:"engine_type" json:"engine_type"`
+	EngineVolume string             `bson:"engine_volume" json:"engine_volume"`
+	Mileage      string             `bson:"mileage" json:"mileage"`
+
+	// Разобранные значения ответов (исходный текст хранится в полях выше)
+	YearNum       int     `bson:"year_num,omitempty" json:"year_num,omitempty"`
+	EngineVolumeL float64 `bson:"engine_volume_l,omitempty" json:"engine_volume_l,omitempty"`
+	MileageKm     int     `bson:"mileage_km,omitempty" json:"mileage_km,omitempty"`
 
 	// Третий этап - информация о проблеме
 	Problem              string `bson:"problem" json:"problem"`
@@ -45,16 +72,159 @@ type ServiceRequest struct {
 	PreviousRepairs      string `bson:"previous_repairs" json:"previous_repairs"`
 	RecentChanges        string `bson:"recent_changes" json:"recent_changes"`
 
+	// Фото, видео, голосовые сообщения и документы, приложенные к описанию проблемы
+	Attachments []Attachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
+
+	// Ответы на вопросы анкеты по ID вопроса
+	Answers map[string]string `bson:"answers,omitempty" json:"answers,omitempty"`
+
+	// Сводка заявки, которую клиент подтвердил перед выбором даты, и время подтверждения
+	ApprovedSummary string     `bson:"approved_summary,omitempty" json:"approved_summary,omitempty"`
+	ApprovedAt      *time.Time `bson:"approved_at,omitempty" json:"approved_at,omitempty"`
+
 	// Четвертый этап - дата записи
-	AppointmentDate time.Time `bson:"appointment_date" json:"appointment_date"`
+	AppointmentDate time.Time          `bson:"appointment_date" json:"appointment_date"`
+	AvailableDateID primitive.ObjectID `bson:"available_date_id,omitempty" json:"available_date_id"`
+	TimeSlot        string             `bson:"time_slot,omitempty" json:"time_slot,omitempty"`
+	RemindersSent   []string           `bson:"reminders_sent,omitempty" json:"reminders_sent,omitempty"`
 
 	// Служебная информация
 	Stage     int       `bson:"stage" json:"stage"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
-	Status    string    `bson:"status" json:"status"` // "in_progress", "completed", "cancelled"
+	Status    string    `bson:"status" json:"status"` // см. константы Status*
+
+	// История переходов статуса
+	StatusHistory []StatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
+
+	// Внутренние заметки сотрудников
+	Notes []RequestNote `bson:"notes,omitempty" json:"notes,omitempty"`
 }
 
+// SetAnswer сохраняет ответ на вопрос анкеты и, если задано поле, записывает его в заявку.
+// Возвращает false, если поле заявки неизвестно.
+func (r *ServiceRequest) SetAnswer(questionID, field, value string) bool {
+	if r.Answers == nil {
+		r.Answers = make(map[string]string)
+	}
+	r.Answers[questionID] = value
+
+	return r.SetField(field, value)
+}
+
+// ClearAnswer удаляет ответ на вопрос анкеты вместе со значением поля заявки и разобранными из него данными
+func (r *ServiceRequest) ClearAnswer(questionID, field string) {
+	delete(r.Answers, questionID)
+	r.SetField(field, "")
+
+	switch field {
+	case "contact":
+		r.Phone = ""
+	case "volvo_model":
+		r.ModelID = ""
+	case "year":
+		r.YearNum = 0
+	case "engine_volume":
+		r.EngineVolumeL = 0
+	case "mileage":
+		r.MileageKm = 0
+	}
+}
+
+// KnownField сообщает, можно ли записать ответ в поле заявки с таким именем
+func KnownField(field string) bool {
+	var r ServiceRequest
+	return r.SetField(field, "")
+}
+
+// SetField записывает значение в поле заявки по имени. Возвращает false, если поле неизвестно.
+func (r *ServiceRequest) SetField(field, value string) bool {
+	switch field {
+	case "":
+	case "name":
+		r.Name = value
+	case "contact":
+		r.Contact = value
+	case "vin":
+		r.VIN = value
+	case "volvo_model":
+		r.VolvoModel = value
+	case "year":
+		r.Year = value
+	case "engine_type":
+		r.EngineType = value
+	case "engine_volume":
+		r.EngineVolume = value
+	case "mileage":
+		r.Mileage = value
+	case "problem":
+		r.Problem = value
+	case "problem_first_appeared":
+		r.ProblemFirstAppeared = value
+	case "problem_frequency":
+		r.ProblemFrequency = value
+	case "safety_impact":
+		r.SafetyImpact = value
+	case "previous_repairs":
+		r.PreviousRepairs = value
+	case "recent_changes":
+		r.RecentChanges = value
+	default:
+		return false
+	}
+	return true
+}
+
+// Attachment представляет файл, приложенный клиентом к заявке
+type Attachment struct {
+	Type         string    `bson:"type" json:"type"` // см. константы Attachment*
+	FileID       string    `bson:"file_id" json:"file_id"`
+	FileUniqueID string    `bson:"file_unique_id" json:"file_unique_id"`
+	FileName     string    `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	MimeType     string    `bson:"mime_type,omitempty" json:"mime_type,omitempty"`
+	Size         int       `bson:"size,omitempty" json:"size,omitempty"`
+	Caption      string    `bson:"caption,omitempty" json:"caption,omitempty"`
+	StorageKey   string    `bson:"storage_key,omitempty" json:"storage_key,omitempty"` // ключ в хранилище вложений, если файл скачан
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Типы вложений
+const (
+	AttachmentPhoto     = "photo"
+	AttachmentVideo     = "video"
+	AttachmentVideoNote = "video_note"
+	AttachmentVoice     = "voice"
+	AttachmentAudio     = "audio"
+	AttachmentDocument  = "document"
+)
+
+// RequestNote представляет внутреннюю заметку к заявке
+type RequestNote struct {
+	Author    string    `bson:"author" json:"author"`
+	Text      string    `bson:"text" json:"text"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// StatusChange представляет запись в истории статусов заявки
+type StatusChange struct {
+	From   string    `bson:"from" json:"from"`
+	To     string    `bson:"to" json:"to"`
+	Actor  string    `bson:"actor" json:"actor"`
+	Reason string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	At     time.Time `bson:"at" json:"at"`
+}
+
+// Статусы заявки
+const (
+	StatusDraft     = "draft"      // заявка заполняется
+	StatusBooked    = "booked"     // клиент записан на время
+	StatusConfirmed = "confirmed"  // клиент подтвердил визит
+	StatusInService = "in_service" // автомобиль в работе
+	StatusDone      = "done"       // работы завершены
+	StatusCancelled = "cancelled"  // заявка отменена
+	StatusNoShow    = "no_show"    // клиент не приехал
+)
+
 // AvailableDate представляет доступную дату для записи
 type AvailableDate struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -67,20 +237,113 @@ type AvailableDate struct {
 
 // TimeSlot представляет временной слот
 type TimeSlot struct {
-	Time     string `bson:"time" json:"time"`
-	IsBooked bool   `bson:"is_booked" json:"is_booked"`
+	Time      string             `bson:"time" json:"time"`
+	IsBooked  bool               `bson:"is_booked" json:"is_booked"`
+	RequestID primitive.ObjectID `bson:"request_id,omitempty" json:"request_id"`
 }
 
 // UserSession представляет сессию пользователя
 type UserSession struct {
-	UserID    int64                  `bson:"user_id" json:"user_id"`
-	ChatID    int64                  `bson:"chat_id" json:"chat_id"`
-	Stage     int                    `bson:"stage" json:"stage"`
-	RequestID primitive.ObjectID     `bson:"request_id,omitempty" json:"request_id"`
-	Data      map[string]interface{} `bson:"data" json:"data"`
-	UpdatedAt time.Time              `bson:"updated_at" json:"updated_at"`
+	UserID     int64                  `bson:"user_id" json:"user_id"`
+	ChatID     int64                  `bson:"chat_id" json:"chat_id"`
+	Stage      int                    `bson:"stage" json:"stage"`
+	RequestID  primitive.ObjectID     `bson:"request_id,omitempty" json:"request_id"`
+	QuestionID string                 `bson:"question_id,omitempty" json:"question_id,omitempty"` // текущий вопрос анкеты
+	Data       map[string]interface{} `bson:"data" json:"data"`
+	NudgedAt   *time.Time             `bson:"nudged_at,omitempty" json:"nudged_at,omitempty"` // когда клиенту предложено продолжить заявку
+	UpdatedAt  time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// Vehicle представляет автомобиль клиента, сохраненный между заявками
+type Vehicle struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        int64              `bson:"user_id" json:"user_id"`
+	VIN           string             `bson:"vin,omitempty" json:"vin,omitempty"`
+	ModelID       string             `bson:"model_id,omitempty" json:"model_id,omitempty"`
+	VolvoModel    string             `bson:"volvo_model" json:"volvo_model"`
+	Year          string             `bson:"year" json:"year"`
+	YearNum       int                `bson:"year_num,omitempty" json:"year_num,omitempty"`
+	EngineType    string             `bson:"engine_type" json:"engine_type"`
+	EngineVolume  string             `bson:"engine_volume" json:"engine_volume"`
+	EngineVolumeL float64            `bson:"engine_volume_l,omitempty" json:"engine_volume_l,omitempty"`
+	Mileage       string             `bson:"mileage" json:"mileage"`
+	MileageKm     int                `bson:"mileage_km,omitempty" json:"mileage_km,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Title возвращает короткое название автомобиля, например "XC60 2017"
+func (v *Vehicle) Title() string {
+	if v.Year == "" {
+		return v.VolvoModel
+	}
+	return v.VolvoModel + " " + v.Year
 }
 
+// VolvoModel представляет модель из каталога Volvo
+type VolvoModel struct {
+	ID          string            `bson:"_id" json:"id"` // например "xc90"
+	Name        string            `bson:"name" json:"name"`
+	Generations []ModelGeneration `bson:"generations" json:"generations"`
+}
+
+// ModelGeneration представляет поколение модели
+type ModelGeneration struct {
+	Name          string    `bson:"name" json:"name"`
+	YearFrom      int       `bson:"year_from" json:"year_from"`
+	YearTo        int       `bson:"year_to,omitempty" json:"year_to,omitempty"` // 0 - выпускается до сих пор
+	EngineTypes   []string  `bson:"engine_types" json:"engine_types"`
+	EngineVolumes []float64 `bson:"engine_volumes,omitempty" json:"engine_volumes,omitempty"` // в литрах
+}
+
+// Generation возвращает поколение, выпускавшееся в указанном году, или nil
+func (m *VolvoModel) Generation(year int) *ModelGeneration {
+	for i := range m.Generations {
+		g := &m.Generations[i]
+		if year >= g.YearFrom && (g.YearTo == 0 || year <= g.YearTo) {
+			return g
+		}
+	}
+	return nil
+}
+
+// Admin представляет сотрудника с доступом к админ-панели
+type Admin struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username     string             `bson:"username" json:"username"`
+	PasswordHash string             `bson:"password_hash,omitempty" json:"-"`
+	TelegramID   int64              `bson:"telegram_id,omitempty" json:"telegram_id,omitempty"`
+	Role         string             `bson:"role" json:"role"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// AdminSession представляет сессию входа в админ-панель
+type AdminSession struct {
+	Token     string             `bson:"_id" json:"-"`
+	AdminID   primitive.ObjectID `bson:"admin_id" json:"admin_id"`
+	Username  string             `bson:"username" json:"username"`
+	Role      string             `bson:"role" json:"role"`
+	CSRFToken string             `bson:"csrf_token" json:"csrf_token"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AdminLoginCode представляет одноразовый код входа в админ-панель, выданный ботом
+type AdminLoginCode struct {
+	CodeHash   string    `bson:"_id" json:"-"`
+	TelegramID int64     `bson:"telegram_id" json:"telegram_id"`
+	Username   string    `bson:"username,omitempty" json:"username,omitempty"`
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Роли сотрудников
+const (
+	RoleManager = "manager" // управляет расписанием и заявками
+	RoleViewer  = "viewer"  // только просмотр
+)
+
 // BotStages константы для этапов
 const (
 	StageStart = iota
@@ -89,31 +352,9 @@ const (
 	StageProblemInfo
 	StageDateSelection
 	StageCompleted
+	StageReview // проверка ответов перед выбором даты
 )
 
-// EngineTypes доступные типы двигателей
-var EngineTypes = []string{
-	"Бензин",
-	"Дизель",
-	"Гибрид",
-	"Электро",
-}
-
-// ProblemFrequencies варианты частоты проблемы
-var ProblemFrequencies = []string{
-	"Постоянно",
-	"Периодически",
-	"Только при определенных условиях",
-}
-
-// ProblemAppeared варианты когда впервые появилась проблема
-var ProblemAppeared = []string{
-	"Недавно",
-	"Давно",
-	"Только сегодня",
-	"Не помню",
-}
-
 // DefaultTimeSlots стандартные временные слоты
 var DefaultTimeSlots = []TimeSlot{
 	{Time: "09:00", IsBooked: false},
@@ -126,53 +367,3 @@ var DefaultTimeSlots = []TimeSlot{
 	{Time: "16:00", IsBooked: false},
 	{Time: "17:00", IsBooked: false},
 }
-
-// Маппинги для callback data (английские ключи для русских значений)
-
-// ProblemAppearedKeys маппинг русских значений на английские ключи
-var ProblemAppearedKeys = map[string]string{
-	"Недавно":        "recently",
-	"Давно":          "long_ago",
-	"Только сегодня": "today",
-	"Не помню":       "dont_remember",
-}
-
-// ProblemFrequencyKeys маппинг русских значений на английские ключи
-var ProblemFrequencyKeys = map[string]string{
-	"Постоянно":    "constantly",
-	"Периодически": "periodically",
-	"Только при определенных условиях": "under_conditions",
-}
-
-// EngineTypeKeys маппинг русских значений на английские ключи
-var EngineTypeKeys = map[string]string{
-	"Бензин":  "petrol",
-	"Дизель":  "diesel",
-	"Гибрид":  "hybrid",
-	"Электро": "electric",
-}
-
-// Обратные маппинги (английские ключи на русские значения)
-
-// ProblemAppearedValues маппинг английских ключей на русские значения
-var ProblemAppearedValues = map[string]string{
-	"recently":      "Недавно",
-	"long_ago":      "Давно",
-	"today":         "Только сегодня",
-	"dont_remember": "Не помню",
-}
-
-// ProblemFrequencyValues маппинг английских ключей на русские значения
-var ProblemFrequencyValues = map[string]string{
-	"constantly":       "Постоянно",
-	"periodically":     "Периодически",
-	"under_conditions": "Только при определенных условиях",
-}
-
-// EngineTypeValues маппинг английских ключей на русские значения
-var EngineTypeValues = map[string]string{
-	"petrol":   "Бензин",
-	"diesel":   "Дизель",
-	"hybrid":   "Гибрид",
-	"electric": "Электро",
-}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"volvomaster/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sender отправляет текстовые уведомления клиентам
+type Sender interface {
+	Notify(chatID int64, text string) error
+}
+
+// TelegramSender отправляет уведомления через Telegram Bot API
+type TelegramSender struct {
+	api *tgbotapi.BotAPI
+}
+
+func NewTelegramSender(api *tgbotapi.BotAPI) *TelegramSender {
+	return &TelegramSender{api: api}
+}
+
+// NewTelegramSenderFromToken создает отправителя для процессов, которые не запускают бота (например, админ-панель)
+func NewTelegramSenderFromToken(token string) (*TelegramSender, error) {
+	api, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания клиента Telegram: %w", err)
+	}
+	return NewTelegramSender(api), nil
+}
+
+func (s *TelegramSender) Notify(chatID int64, text string) error {
+	_, err := s.api.Send(tgbotapi.NewMessage(chatID, text))
+	return err
+}
+
+// FileURL возвращает ссылку на файл из Telegram по file ID.
+// Ссылка содержит токен бота, поэтому ее нельзя показывать пользователям.
+func (s *TelegramSender) FileURL(fileID string) (string, error) {
+	return s.api.GetFileDirectURL(fileID)
+}
+
+// Template шаблон уведомления клиенту
+type Template struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// Templates доступные шаблоны уведомлений. В тексте доступны поля models.ServiceRequest.
+var Templates = []Template{
+	{
+		Key:   "confirmed",
+		Title: "Запись подтверждена",
+		Text:  `Здравствуйте, {{.Name}}! Ваша запись в сервисный центр Volvo на {{.AppointmentDate.Format "02.01.2006 в 15:04"}} подтверждена. Ждем вас!`,
+	},
+	{
+		Key:   "in_service",
+		Title: "Автомобиль принят в работу",
+		Text:  `{{.Name}}, ваш автомобиль Volvo {{.VolvoModel}} принят в работу. Мы сообщим, когда он будет готов.`,
+	},
+	{
+		Key:   "ready",
+		Title: "Автомобиль готов",
+		Text:  `{{.Name}}, ваш автомобиль Volvo {{.VolvoModel}} готов! Его можно забирать.`,
+	},
+	{
+		Key:   "cancelled",
+		Title: "Запись отменена",
+		Text:  `{{.Name}}, к сожалению, ваша запись на {{.AppointmentDate.Format "02.01.2006 в 15:04"}} отменена. Для новой записи нажмите /start.`,
+	},
+}
+
+// Render подставляет данные заявки в шаблон с указанным ключом
+func Render(key string, request *models.ServiceRequest) (string, error) {
+	for _, t := range Templates {
+		if t.Key != key {
+			continue
+		}
+
+		tmpl, err := template.New(t.Key).Parse(t.Text)
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, request); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	return "", fmt.Errorf("шаблон уведомления %q не найден", key)
+}
@@ -0,0 +1,355 @@
+package questionnaire
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"volvomaster/internal/models"
+)
+
+// End значение Next, завершающее анкету
+const End = "end"
+
+// maxCallbackData ограничение Telegram на длину callback data
+const maxCallbackData = 64
+
+// InputType тип ответа на вопрос
+type InputType string
+
+const (
+	InputText    InputType = "text"
+	InputChoice  InputType = "choice"
+	InputContact InputType = "contact"
+	InputPhoto   InputType = "photo"
+)
+
+// Definition описывает анкету: разделы и упорядоченный список вопросов
+type Definition struct {
+	ID        string     `json:"id" bson:"_id"`
+	Title     string     `json:"title,omitempty" bson:"title,omitempty"`
+	Sections  []Section  `json:"sections" bson:"sections"`
+	Questions []Question `json:"questions" bson:"questions"`
+}
+
+// Section раздел анкеты (контакты, автомобиль, проблема)
+type Section struct {
+	ID    string `json:"id" bson:"id"`
+	Title string `json:"title" bson:"title"`
+	Intro string `json:"intro,omitempty" bson:"intro,omitempty"` // текст перед первым вопросом раздела
+}
+
+// Question вопрос анкеты
+type Question struct {
+	ID         string      `json:"id" bson:"id"`
+	Section    string      `json:"section" bson:"section"`
+	Field      string      `json:"field,omitempty" bson:"field,omitempty"` // поле заявки, в которое записывается ответ
+	Title      string      `json:"title,omitempty" bson:"title,omitempty"` // короткое название для проверки заявки
+	Text       string      `json:"text" bson:"text"`
+	Type       InputType   `json:"type" bson:"type"`
+	Options    []Option    `json:"options,omitempty" bson:"options,omitempty"`
+	Validation *Validation `json:"validation,omitempty" bson:"validation,omitempty"`
+	Next       string      `json:"next,omitempty" bson:"next,omitempty"`         // по умолчанию следующий вопрос по списку
+	Optional   bool        `json:"optional,omitempty" bson:"optional,omitempty"` // вопрос можно пропустить
+}
+
+// Форматы ответов, которые разбираются и сохраняются в типизированные поля заявки
+const (
+	FormatYear         = "year"          // год выпуска
+	FormatEngineVolume = "engine_volume" // объем двигателя
+	FormatMileage      = "mileage"       // пробег
+	FormatContact      = "contact"       // телефон или @username
+	FormatVolvoModel   = "volvo_model"   // модель из каталога, вопрос показывается с выбором модели
+	FormatVIN          = "vin"           // VIN, по которому заполняются данные об автомобиле
+)
+
+var formats = map[string]bool{
+	FormatYear:         true,
+	FormatEngineVolume: true,
+	FormatMileage:      true,
+	FormatContact:      true,
+	FormatVolvoModel:   true,
+	FormatVIN:          true,
+}
+
+// Option вариант ответа для вопроса с выбором
+type Option struct {
+	Key   string `json:"key" bson:"key"`
+	Label string `json:"label" bson:"label"`
+	Next  string `json:"next,omitempty" bson:"next,omitempty"` // переход при выборе варианта
+}
+
+// Validation правила проверки текстового ответа
+type Validation struct {
+	MinLength int    `json:"min_length,omitempty" bson:"min_length,omitempty"`
+	MaxLength int    `json:"max_length,omitempty" bson:"max_length,omitempty"`
+	Pattern   string `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	Format    string `json:"format,omitempty" bson:"format,omitempty"`   // см. константы Format*
+	Message   string `json:"message,omitempty" bson:"message,omitempty"` // текст ошибки для пользователя
+
+	pattern *regexp.Regexp // Pattern, скомпилированный в Validate
+}
+
+//go:embed default.json
+var defaultDefinition []byte
+
+// Default возвращает встроенную анкету сервисного центра Volvo
+func Default() *Definition {
+	def, err := Parse(defaultDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("встроенная анкета некорректна: %v", err))
+	}
+	return def
+}
+
+// LoadFile загружает анкету из JSON-файла
+func LoadFile(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse разбирает анкету из JSON и проверяет ее корректность
+func Parse(data []byte) (*Definition, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("ошибка разбора анкеты: %w", err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// Validate проверяет ссылки между вопросами, поля заявки, варианты ответов и правила проверки.
+// Шаблоны проверки компилируются здесь один раз, поэтому анкету нужно проверить до использования.
+func (d *Definition) Validate() error {
+	if len(d.Questions) == 0 {
+		return errors.New("анкета не содержит вопросов")
+	}
+
+	sections := make(map[string]bool)
+	for _, section := range d.Sections {
+		sections[section.ID] = true
+	}
+
+	ids := make(map[string]bool)
+	for _, q := range d.Questions {
+		if q.ID == "" || q.ID == End || strings.Contains(q.ID, ":") {
+			return fmt.Errorf("недопустимый ID вопроса %q", q.ID)
+		}
+		if ids[q.ID] {
+			return fmt.Errorf("вопрос %q объявлен дважды", q.ID)
+		}
+		ids[q.ID] = true
+	}
+
+	for _, q := range d.Questions {
+		if !sections[q.Section] {
+			return fmt.Errorf("вопрос %q: неизвестный раздел %q", q.ID, q.Section)
+		}
+		if !validNext(q.Next, ids) {
+			return fmt.Errorf("вопрос %q: неизвестный переход %q", q.ID, q.Next)
+		}
+		if !models.KnownField(q.Field) {
+			return fmt.Errorf("вопрос %q: неизвестное поле заявки %q", q.ID, q.Field)
+		}
+
+		switch q.Type {
+		case InputText, InputContact, InputPhoto:
+			if len(q.Options) > 0 {
+				return fmt.Errorf("вопрос %q: варианты допустимы только для типа %q", q.ID, InputChoice)
+			}
+		case InputChoice:
+			if len(q.Options) == 0 {
+				return fmt.Errorf("вопрос %q: не заданы варианты ответа", q.ID)
+			}
+			for _, option := range q.Options {
+				if option.Key == "" || strings.Contains(option.Key, ":") {
+					return fmt.Errorf("вопрос %q: недопустимый ключ варианта %q", q.ID, option.Key)
+				}
+				if len(CallbackData(q.ID, option.Key)) > maxCallbackData {
+					return fmt.Errorf("вопрос %q: слишком длинный ключ варианта %q", q.ID, option.Key)
+				}
+				if !validNext(option.Next, ids) {
+					return fmt.Errorf("вопрос %q: неизвестный переход %q", q.ID, option.Next)
+				}
+			}
+		default:
+			return fmt.Errorf("вопрос %q: неизвестный тип %q", q.ID, q.Type)
+		}
+
+		if q.Validation != nil && q.Validation.Format != "" && !formats[q.Validation.Format] {
+			return fmt.Errorf("вопрос %q: неизвестный формат ответа %q", q.ID, q.Validation.Format)
+		}
+		if q.Validation != nil && q.Validation.Pattern != "" {
+			pattern, err := regexp.Compile(q.Validation.Pattern)
+			if err != nil {
+				return fmt.Errorf("вопрос %q: неверный шаблон проверки: %w", q.ID, err)
+			}
+			q.Validation.pattern = pattern
+		}
+	}
+
+	return nil
+}
+
+func validNext(next string, ids map[string]bool) bool {
+	return next == "" || next == End || ids[next]
+}
+
+// First возвращает первый вопрос анкеты
+func (d *Definition) First() *Question {
+	return &d.Questions[0]
+}
+
+// Question возвращает вопрос по ID или nil
+func (d *Definition) Question(id string) *Question {
+	for i := range d.Questions {
+		if d.Questions[i].ID == id {
+			return &d.Questions[i]
+		}
+	}
+	return nil
+}
+
+// Section возвращает раздел по ID или nil
+func (d *Definition) Section(id string) *Section {
+	for i := range d.Sections {
+		if d.Sections[i].ID == id {
+			return &d.Sections[i]
+		}
+	}
+	return nil
+}
+
+// QuestionByField возвращает вопрос, ответ на который записывается в указанное поле заявки, или nil
+func (d *Definition) QuestionByField(field string) *Question {
+	for i := range d.Questions {
+		if d.Questions[i].Field == field {
+			return &d.Questions[i]
+		}
+	}
+	return nil
+}
+
+// Path возвращает вопросы в порядке прохождения анкеты с учетом данных ответов.
+// Путь заканчивается первым вопросом без ответа или последним вопросом анкеты.
+func (d *Definition) Path(answers map[string]string) []*Question {
+	var path []*Question
+	visited := make(map[string]bool)
+
+	for q := d.First(); q != nil && !visited[q.ID]; {
+		visited[q.ID] = true
+		path = append(path, q)
+
+		answer, answered := answers[q.ID]
+		if !answered {
+			break
+		}
+		q = d.Next(q, q.OptionByLabel(answer))
+	}
+	return path
+}
+
+// Next возвращает вопрос, следующий за q с учетом выбранного варианта, или nil в конце анкеты
+func (d *Definition) Next(q *Question, option *Option) *Question {
+	next := q.Next
+	if option != nil && option.Next != "" {
+		next = option.Next
+	}
+
+	if next == End {
+		return nil
+	}
+	if next != "" {
+		return d.Question(next)
+	}
+
+	// По умолчанию переходим к следующему вопросу по списку
+	for i := range d.Questions {
+		if d.Questions[i].ID == q.ID && i+1 < len(d.Questions) {
+			return &d.Questions[i+1]
+		}
+	}
+	return nil
+}
+
+// Label возвращает короткое название вопроса, а если оно не задано - текст вопроса
+func (q *Question) Label() string {
+	if q.Title != "" {
+		return q.Title
+	}
+	return q.Text
+}
+
+// Option возвращает вариант ответа по ключу или nil
+func (q *Question) Option(key string) *Option {
+	for i := range q.Options {
+		if q.Options[i].Key == key {
+			return &q.Options[i]
+		}
+	}
+	return nil
+}
+
+// OptionByLabel ищет вариант по тексту без учета регистра (если пользователь ввел его вручную)
+func (q *Question) OptionByLabel(label string) *Option {
+	label = strings.TrimSpace(label)
+	for i := range q.Options {
+		if strings.EqualFold(q.Options[i].Label, label) {
+			return &q.Options[i]
+		}
+	}
+	return nil
+}
+
+// Check проверяет ответ по правилам вопроса и возвращает ошибку с текстом для пользователя
+func (q *Question) Check(answer string) error {
+	v := q.Validation
+	if v == nil {
+		return nil
+	}
+
+	length := utf8.RuneCountInString(strings.TrimSpace(answer))
+	valid := (v.MinLength == 0 || length >= v.MinLength) &&
+		(v.MaxLength == 0 || length <= v.MaxLength)
+
+	if valid && v.pattern != nil {
+		valid = v.pattern.MatchString(answer)
+	}
+
+	if valid {
+		return nil
+	}
+	if v.Message != "" {
+		return errors.New(v.Message)
+	}
+	return errors.New("Ответ не подходит, попробуйте еще раз.")
+}
+
+// CallbackData возвращает callback data кнопки варианта ответа
+func CallbackData(questionID, optionKey string) string {
+	return "qa:" + questionID + ":" + optionKey
+}
+
+// SkipCallbackData возвращает callback data кнопки пропуска необязательного вопроса
+func SkipCallbackData(questionID string) string {
+	return CallbackData(questionID, "")
+}
+
+// ParseCallbackData разбирает callback data кнопки варианта ответа
+func ParseCallbackData(data string) (questionID, optionKey string, ok bool) {
+	parts := strings.SplitN(data, ":", 3)
+	if len(parts) != 3 || parts[0] != "qa" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
@@ -0,0 +1,59 @@
+package questionnaire
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{
+			name: "корректная анкета",
+			json: `{"sections":[{"id":"s"}],"questions":[{"id":"q","section":"s","field":"name","type":"text"}]}`,
+		},
+		{
+			name:    "неизвестное поле заявки",
+			json:    `{"sections":[{"id":"s"}],"questions":[{"id":"q","section":"s","field":"nickname","type":"text"}]}`,
+			wantErr: "неизвестное поле заявки",
+		},
+		{
+			name:    "неверный шаблон",
+			json:    `{"sections":[{"id":"s"}],"questions":[{"id":"q","section":"s","type":"text","validation":{"pattern":"("}}]}`,
+			wantErr: "неверный шаблон проверки",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.json))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse: ошибка %v, ожидалась %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPattern(t *testing.T) {
+	def, err := Parse([]byte(`{"sections":[{"id":"s"}],"questions":[{"id":"q","section":"s","type":"text","validation":{"pattern":"^\\d+$","message":"Только цифры"}}]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	q := def.First()
+	if err := q.Check("123"); err != nil {
+		t.Errorf("Check(123): %v", err)
+	}
+	if err := q.Check("abc"); err == nil || err.Error() != "Только цифры" {
+		t.Errorf("Check(abc): %v, ожидалось «Только цифры»", err)
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"volvomaster/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AdminSessionTTL время жизни сессии админ-панели
+const AdminSessionTTL = 12 * time.Hour
+
+// AdminLoginCodeTTL время жизни одноразового кода входа из Telegram
+const AdminLoginCodeTTL = 5 * time.Minute
+
+// loginCodeAlphabet символы кода входа без похожих друг на друга (0/O, 1/I)
+const loginCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var (
+	// ErrInvalidCredentials возвращается при неверном логине или пароле
+	ErrInvalidCredentials = errors.New("неверный логин или пароль")
+	// ErrInvalidLoginCode возвращается при неверном, истекшем или уже использованном коде входа
+	ErrInvalidLoginCode = errors.New("неверный или просроченный код входа")
+	// ErrAdminExists возвращается при создании сотрудника с занятым логином
+	ErrAdminExists = errors.New("сотрудник с таким логином уже существует")
+)
+
+// EnsureAdminIndexes создает индексы коллекций админ-панели.
+// Просроченные сессии удаляются MongoDB автоматически по TTL-индексу.
+func (s *DatabaseService) EnsureAdminIndexes(ctx context.Context) error {
+	_, err := s.admins.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.adminSessions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.adminLogins.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// CreateAdmin создает сотрудника с хешированным паролем
+func (s *DatabaseService) CreateAdmin(ctx context.Context, username, password, role string) (*models.Admin, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &models.Admin{
+		ID:           primitive.NewObjectID(),
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         role,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if _, err := s.admins.InsertOne(ctx, admin); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAdminExists
+		}
+		return nil, err
+	}
+	return admin, nil
+}
+
+func (s *DatabaseService) GetAdmins(ctx context.Context) ([]*models.Admin, error) {
+	cursor, err := s.admins.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "username", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var admins []*models.Admin
+	for cursor.Next(ctx) {
+		var admin models.Admin
+		if err := cursor.Decode(&admin); err != nil {
+			continue
+		}
+		admins = append(admins, &admin)
+	}
+
+	return admins, cursor.Err()
+}
+
+func (s *DatabaseService) CountAdmins(ctx context.Context) (int64, error) {
+	return s.admins.CountDocuments(ctx, bson.M{})
+}
+
+// AuthenticateAdmin проверяет логин и пароль сотрудника
+func (s *DatabaseService) AuthenticateAdmin(ctx context.Context, username, password string) (*models.Admin, error) {
+	var admin models.Admin
+	err := findOne(ctx, s.admins, bson.M{"username": username}, &admin)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &admin, nil
+}
+
+// CreateAdminSession создает сессию входа со случайными токенами сессии и CSRF
+func (s *DatabaseService) CreateAdminSession(ctx context.Context, admin *models.Admin) (*models.AdminSession, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.AdminSession{
+		Token:     token,
+		AdminID:   admin.ID,
+		Username:  admin.Username,
+		Role:      admin.Role,
+		CSRFToken: csrfToken,
+		ExpiresAt: time.Now().Add(AdminSessionTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := s.adminSessions.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetAdminSession получает действующую сессию по токену
+func (s *DatabaseService) GetAdminSession(ctx context.Context, token string) (*models.AdminSession, error) {
+	var session models.AdminSession
+	filter := bson.M{
+		"_id":        token,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	err := findOne(ctx, s.adminSessions, filter, &session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *DatabaseService) DeleteAdminSession(ctx context.Context, token string) error {
+	_, err := s.adminSessions.DeleteOne(ctx, bson.M{"_id": token})
+	return err
+}
+
+// CreateAdminLoginCode выдает одноразовый код входа для сотрудника Telegram.
+// В базе хранится только хеш кода.
+func (s *DatabaseService) CreateAdminLoginCode(ctx context.Context, telegramID int64, username string) (string, error) {
+	code, err := newLoginCode()
+	if err != nil {
+		return "", err
+	}
+
+	login := &models.AdminLoginCode{
+		CodeHash:   hashLoginCode(code),
+		TelegramID: telegramID,
+		Username:   username,
+		ExpiresAt:  time.Now().Add(AdminLoginCodeTTL),
+		CreatedAt:  time.Now(),
+	}
+
+	if _, err := s.adminLogins.InsertOne(ctx, login); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ConsumeAdminLoginCode атомарно проверяет и удаляет код входа, чтобы его нельзя было использовать повторно
+func (s *DatabaseService) ConsumeAdminLoginCode(ctx context.Context, code string) (*models.AdminLoginCode, error) {
+	var login models.AdminLoginCode
+	filter := bson.M{
+		"_id":        hashLoginCode(normalizeLoginCode(code)),
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.adminLogins.FindOneAndDelete(ctx, filter).Decode(&login)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidLoginCode
+		}
+		return nil, err
+	}
+	return &login, nil
+}
+
+// GetOrCreateTelegramAdmin находит сотрудника по Telegram ID или создает сотрудника без пароля с ролью role
+func (s *DatabaseService) GetOrCreateTelegramAdmin(ctx context.Context, telegramID int64, role string) (*models.Admin, error) {
+	var admin models.Admin
+	err := findOne(ctx, s.admins, bson.M{"telegram_id": telegramID}, &admin)
+	if err == nil {
+		return &admin, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	created := newTelegramAdmin(telegramID, role)
+	if _, err := s.admins.InsertOne(ctx, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// newTelegramAdmin создает сотрудника без пароля, входящего через Telegram.
+// Имя строится по Telegram ID: username в Telegram можно сменить, и его займет кто-то другой.
+func newTelegramAdmin(telegramID int64, role string) *models.Admin {
+	return &models.Admin{
+		ID:         primitive.NewObjectID(),
+		Username:   fmt.Sprintf("tg:%d", telegramID),
+		TelegramID: telegramID,
+		Role:       role,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
+
+// newLoginCode генерирует одноразовый код входа
+func newLoginCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, len(buf))
+	for i, b := range buf {
+		code[i] = loginCodeAlphabet[int(b)%len(loginCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// normalizeLoginCode приводит введенный сотрудником код к виду, в котором он был выдан
+func normalizeLoginCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func hashLoginCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken возвращает криптографически случайный токен в hex
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+
+	"volvomaster/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SeedVolvoModels добавляет в каталог недостающие модели.
+// Уже существующие записи не перезаписываются, чтобы сохранить правки сотрудников.
+func (s *DatabaseService) SeedVolvoModels(ctx context.Context, catalog []models.VolvoModel) error {
+	for _, model := range catalog {
+		_, err := s.volvoModels.UpdateOne(ctx,
+			bson.M{"_id": model.ID},
+			bson.M{"$setOnInsert": model},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVolvoModels получает каталог моделей, отсортированный по названию
+func (s *DatabaseService) GetVolvoModels(ctx context.Context) ([]models.VolvoModel, error) {
+	cursor, err := s.volvoModels.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var catalog []models.VolvoModel
+	if err := cursor.All(ctx, &catalog); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// GetVolvoModel получает модель из каталога по ID
+func (s *DatabaseService) GetVolvoModel(ctx context.Context, id string) (*models.VolvoModel, error) {
+	var model models.VolvoModel
+	if err := findOne(ctx, s.volvoModels, bson.M{"_id": id}, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"volvomaster/internal/database"
@@ -13,6 +15,18 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// findOne декодирует в v первый документ по filter. Если документа нет, возвращает ErrNotFound.
+func findOne(ctx context.Context, collection *mongo.Collection, filter interface{}, v interface{}) error {
+	err := collection.FindOne(ctx, filter).Decode(v)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ErrSlotUnavailable возвращается, если слот уже занят или не существует
+var ErrSlotUnavailable = errors.New("временной слот недоступен")
+
 type DatabaseService struct {
 	client         *mongo.Client
 	db             *mongo.Database
@@ -20,6 +34,13 @@ type DatabaseService struct {
 	sessions       *mongo.Collection
 	users          *mongo.Collection
 	availableDates *mongo.Collection
+	admins         *mongo.Collection
+	adminSessions  *mongo.Collection
+	adminLogins    *mongo.Collection
+	questionnaires *mongo.Collection
+	volvoModels    *mongo.Collection
+	vehicles       *mongo.Collection
+	userLocks      *mongo.Collection
 }
 
 func NewDatabaseService(client *mongo.Client) *DatabaseService {
@@ -32,6 +53,13 @@ func NewDatabaseService(client *mongo.Client) *DatabaseService {
 		sessions:       database.GetCollection(db, "user_sessions"),
 		users:          database.GetCollection(db, "users"),
 		availableDates: database.GetCollection(db, "available_dates"),
+		admins:         database.GetCollection(db, "admins"),
+		adminSessions:  database.GetCollection(db, "admin_sessions"),
+		adminLogins:    database.GetCollection(db, "admin_login_codes"),
+		questionnaires: database.GetCollection(db, "questionnaires"),
+		volvoModels:    database.GetCollection(db, "volvo_models"),
+		vehicles:       database.GetCollection(db, "vehicles"),
+		userLocks:      database.GetCollection(db, "user_locks"),
 	}
 }
 
@@ -39,7 +67,7 @@ func NewDatabaseService(client *mongo.Client) *DatabaseService {
 func (s *DatabaseService) SaveUser(ctx context.Context, user *models.User) error {
 	// Проверяем, существует ли пользователь
 	existingUser, err := s.GetUser(ctx, user.UserID)
-	if err != nil && err != mongo.ErrNoDocuments {
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return err
 	}
 
@@ -47,6 +75,17 @@ func (s *DatabaseService) SaveUser(ctx context.Context, user *models.User) error
 		// Обновляем существующего пользователя
 		user.ID = existingUser.ID
 		user.CreatedAt = existingUser.CreatedAt
+
+		// Контакты и имя из заявки приходят не с каждым сообщением, не затираем сохраненные
+		if user.Phone == "" {
+			user.Phone = existingUser.Phone
+		}
+		if user.Telegram == "" {
+			user.Telegram = existingUser.Telegram
+		}
+		if user.Name == "" {
+			user.Name = existingUser.Name
+		}
 	} else {
 		// Создаем нового пользователя
 		user.ID = primitive.NewObjectID()
@@ -66,7 +105,7 @@ func (s *DatabaseService) SaveUser(ctx context.Context, user *models.User) error
 
 func (s *DatabaseService) GetUser(ctx context.Context, userID int64) (*models.User, error) {
 	var user models.User
-	err := s.users.FindOne(ctx, bson.M{"user_id": userID}).Decode(&user)
+	err := findOne(ctx, s.users, bson.M{"user_id": userID}, &user)
 	if err != nil {
 		return nil, err
 	}
@@ -118,34 +157,167 @@ func (s *DatabaseService) GetAvailableDates(ctx context.Context) ([]*models.Avai
 
 func (s *DatabaseService) GetAvailableDateByID(ctx context.Context, id primitive.ObjectID) (*models.AvailableDate, error) {
 	var date models.AvailableDate
-	err := s.availableDates.FindOne(ctx, bson.M{"_id": id}).Decode(&date)
+	err := findOne(ctx, s.availableDates, bson.M{"_id": id}, &date)
 	if err != nil {
 		return nil, err
 	}
 	return &date, nil
 }
 
-// ServiceRequest methods
-func (s *DatabaseService) SaveServiceRequest(ctx context.Context, request *models.ServiceRequest) error {
-	if request.ID.IsZero() {
-		request.ID = primitive.NewObjectID()
-		request.CreatedAt = time.Now()
+// ReserveTimeSlot атомарно бронирует слот за заявкой.
+// Слот бронируется только если он свободен (или уже принадлежит этой заявке),
+// иначе возвращается ErrSlotUnavailable.
+func (s *DatabaseService) ReserveTimeSlot(ctx context.Context, dateID primitive.ObjectID, slotTime string, requestID primitive.ObjectID) error {
+	filter := bson.M{
+		"_id":       dateID,
+		"is_active": true,
+		"time_slots": bson.M{"$elemMatch": bson.M{
+			"time": slotTime,
+			"$or": bson.A{
+				bson.M{"is_booked": false},
+				bson.M{"request_id": requestID},
+			},
+		}},
 	}
-	request.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"time_slots.$.is_booked":  true,
+		"time_slots.$.request_id": requestID,
+		"updated_at":              time.Now(),
+	}}
 
-	filter := bson.M{"_id": request.ID}
-	upsert := true
+	result, err := s.availableDates.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSlotUnavailable
+	}
+	return nil
+}
 
-	_, err := s.requests.ReplaceOne(ctx, filter, request, &options.ReplaceOptions{
-		Upsert: &upsert,
-	})
+// ReleaseTimeSlot освобождает слот, если он забронирован указанной заявкой
+func (s *DatabaseService) ReleaseTimeSlot(ctx context.Context, dateID primitive.ObjectID, slotTime string, requestID primitive.ObjectID) error {
+	filter := bson.M{
+		"_id": dateID,
+		"time_slots": bson.M{"$elemMatch": bson.M{
+			"time":       slotTime,
+			"request_id": requestID,
+		}},
+	}
+	update := bson.M{
+		"$set":   bson.M{"time_slots.$.is_booked": false, "updated_at": time.Now()},
+		"$unset": bson.M{"time_slots.$.request_id": ""},
+	}
 
+	_, err := s.availableDates.UpdateOne(ctx, filter, update)
 	return err
 }
 
+// SetTimeSlotBooked вручную закрывает или открывает слот с номером index.
+// Слот, забронированный заявкой, не меняется: его освобождает только отмена или перенос заявки.
+// Если слот занят заявкой или не существует, возвращается ErrSlotUnavailable.
+func (s *DatabaseService) SetTimeSlotBooked(ctx context.Context, dateID primitive.ObjectID, index int, booked bool) error {
+	if index < 0 {
+		return ErrSlotUnavailable
+	}
+
+	slot := fmt.Sprintf("time_slots.%d", index)
+	filter := bson.M{
+		"_id":                dateID,
+		slot + ".time":       bson.M{"$exists": true},
+		slot + ".request_id": bson.M{"$in": bson.A{nil, primitive.NilObjectID}},
+	}
+	update := bson.M{"$set": bson.M{
+		slot + ".is_booked": booked,
+		"updated_at":        time.Now(),
+	}}
+
+	result, err := s.availableDates.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSlotUnavailable
+	}
+	return nil
+}
+
+// SetAvailableDateActive включает или отключает дату для записи, не трогая ее слоты
+func (s *DatabaseService) SetAvailableDateActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	update := bson.M{"$set": bson.M{"is_active": active, "updated_at": time.Now()}}
+
+	result, err := s.availableDates.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ServiceRequest methods
+
+// requestOwnFields - поля заявки, которые бот заполняет по ответам клиента. Статус и его история,
+// бронирование, заметки, вложения и отметки о напоминаниях меняются отдельными методами
+// и при сохранении ответов не перезаписываются.
+var requestOwnFields = []string{
+	"user_id", "chat_id", "name", "contact", "phone",
+	"vin", "volvo_model", "model_id", "vehicle_id", "year", "engine_type", "engine_volume", "mileage",
+	"year_num", "engine_volume_l", "mileage_km",
+	"problem", "problem_first_appeared", "problem_frequency", "safety_impact", "previous_repairs", "recent_changes",
+	"answers", "approved_summary", "approved_at", "stage", "updated_at",
+}
+
+// requestOwnUpdate разбивает поля requestOwnFields на заполненные и пустые (omitempty), которые нужно удалить
+func requestOwnUpdate(request *models.ServiceRequest) (set, unset bson.M, err error) {
+	data, err := bson.Marshal(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	set, unset = bson.M{}, bson.M{}
+	for _, field := range requestOwnFields {
+		if value, ok := doc[field]; ok {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	return set, unset, nil
+}
+
+// SaveServiceRequest сохраняет ответы клиента в существующую заявку (см. requestOwnFields).
+// Заявка создается переходом в статус draft (TransitionServiceRequest).
+func (s *DatabaseService) SaveServiceRequest(ctx context.Context, request *models.ServiceRequest) error {
+	request.UpdatedAt = time.Now()
+
+	set, unset, err := requestOwnUpdate(request)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := s.requests.UpdateOne(ctx, bson.M{"_id": request.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (s *DatabaseService) GetServiceRequest(ctx context.Context, id primitive.ObjectID) (*models.ServiceRequest, error) {
 	var request models.ServiceRequest
-	err := s.requests.FindOne(ctx, bson.M{"_id": id}).Decode(&request)
+	err := findOne(ctx, s.requests, bson.M{"_id": id}, &request)
 	if err != nil {
 		return nil, err
 	}
@@ -156,16 +328,91 @@ func (s *DatabaseService) GetServiceRequestByUserID(ctx context.Context, userID
 	var request models.ServiceRequest
 	filter := bson.M{
 		"user_id": userID,
-		"status":  bson.M{"$ne": "completed"},
+		"status":  models.StatusDraft,
 	}
 
-	err := s.requests.FindOne(ctx, filter).Decode(&request)
+	err := findOne(ctx, s.requests, filter, &request)
 	if err != nil {
 		return nil, err
 	}
 	return &request, nil
 }
 
+// AddServiceRequestNote добавляет внутреннюю заметку к заявке
+func (s *DatabaseService) AddServiceRequestNote(ctx context.Context, requestID primitive.ObjectID, note models.RequestNote) error {
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+
+	update := bson.M{
+		"$push": bson.M{"notes": note},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := s.requests.UpdateOne(ctx, bson.M{"_id": requestID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddServiceRequestAttachment добавляет вложение к заявке
+func (s *DatabaseService) AddServiceRequestAttachment(ctx context.Context, requestID primitive.ObjectID, attachment models.Attachment) error {
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+
+	update := bson.M{
+		"$push": bson.M{"attachments": attachment},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := s.requests.UpdateOne(ctx, bson.M{"_id": requestID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetRequestsDueForReminder получает записанные заявки со временем записи в интервале (from, to],
+// для которых напоминание указанного вида еще не отправлялось
+func (s *DatabaseService) GetRequestsDueForReminder(ctx context.Context, kind string, from, to time.Time) ([]*models.ServiceRequest, error) {
+	filter := bson.M{
+		"status":           bson.M{"$in": bson.A{models.StatusBooked, models.StatusConfirmed}},
+		"appointment_date": bson.M{"$gt": from, "$lte": to},
+		"reminders_sent":   bson.M{"$ne": kind},
+	}
+	return s.findServiceRequests(ctx, filter)
+}
+
+// MarkReminderSent атомарно отмечает напоминание отправленным.
+// Возвращает false, если напоминание уже было отмечено ранее.
+func (s *DatabaseService) MarkReminderSent(ctx context.Context, requestID primitive.ObjectID, kind string) (bool, error) {
+	filter := bson.M{
+		"_id":            requestID,
+		"reminders_sent": bson.M{"$ne": kind},
+	}
+	update := bson.M{"$addToSet": bson.M{"reminders_sent": kind}}
+
+	result, err := s.requests.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UnmarkReminderSent снимает отметку об отправке, чтобы напоминание было отправлено повторно
+func (s *DatabaseService) UnmarkReminderSent(ctx context.Context, requestID primitive.ObjectID, kind string) error {
+	_, err := s.requests.UpdateOne(ctx, bson.M{"_id": requestID}, bson.M{"$pull": bson.M{"reminders_sent": kind}})
+	return err
+}
+
 // UserSession methods
 func (s *DatabaseService) SaveUserSession(ctx context.Context, session *models.UserSession) error {
 	session.UpdatedAt = time.Now()
@@ -182,9 +429,9 @@ func (s *DatabaseService) SaveUserSession(ctx context.Context, session *models.U
 
 func (s *DatabaseService) GetUserSession(ctx context.Context, userID int64) (*models.UserSession, error) {
 	var session models.UserSession
-	err := s.sessions.FindOne(ctx, bson.M{"user_id": userID}).Decode(&session)
+	err := findOne(ctx, s.sessions, bson.M{"user_id": userID}, &session)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, ErrNotFound) {
 			// Создаем новую сессию
 			session = models.UserSession{
 				UserID:    userID,
@@ -204,8 +451,107 @@ func (s *DatabaseService) DeleteUserSession(ctx context.Context, userID int64) e
 	return err
 }
 
-// GetServiceRequests получает все заявки с фильтрацией
-func (s *DatabaseService) GetServiceRequests(ctx context.Context, filter bson.M) ([]*models.ServiceRequest, error) {
+// GetIdleSessions возвращает сессии на указанных этапах, которые не обновлялись с before
+func (s *DatabaseService) GetIdleSessions(ctx context.Context, stages []int, before time.Time) ([]*models.UserSession, error) {
+	filter := bson.M{
+		"stage":      bson.M{"$in": stages},
+		"updated_at": bson.M{"$lte": before},
+	}
+
+	cursor, err := s.sessions.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*models.UserSession
+	for cursor.Next(ctx) {
+		var session models.UserSession
+		if err := cursor.Decode(&session); err != nil {
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, cursor.Err()
+}
+
+// MarkSessionNudged атомарно отмечает, что клиенту предложено продолжить заявку.
+// Возвращает false, если отметка уже есть или сессия обновилась после before.
+func (s *DatabaseService) MarkSessionNudged(ctx context.Context, userID int64, before time.Time) (bool, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"updated_at": bson.M{"$lte": before},
+		"nudged_at":  bson.M{"$exists": false},
+	}
+	// updated_at не меняем, чтобы отметка не продлевала срок жизни сессии
+	update := bson.M{"$set": bson.M{"nudged_at": time.Now()}}
+
+	result, err := s.sessions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ExpireUserSession удаляет сессию, если она не обновлялась с before.
+// Возвращает false, если клиент успел продолжить заполнение.
+func (s *DatabaseService) ExpireUserSession(ctx context.Context, userID int64, before time.Time) (bool, error) {
+	result, err := s.sessions.DeleteOne(ctx, bson.M{
+		"user_id":    userID,
+		"updated_at": bson.M{"$lte": before},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// AcquireUserLock захватывает блокировку клиента, если она свободна, истекла или уже принадлежит owner.
+// Документ блокировки хранится с _id = userID, поэтому занятая блокировка дает ошибку дубликата ключа при upsert.
+func (s *DatabaseService) AcquireUserLock(ctx context.Context, userID int64, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": userID,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}}
+
+	_, err := s.userLocks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseUserLock снимает блокировку клиента, если она принадлежит owner
+func (s *DatabaseService) ReleaseUserLock(ctx context.Context, userID int64, owner string) error {
+	_, err := s.userLocks.DeleteOne(ctx, bson.M{"_id": userID, "owner": owner})
+	return err
+}
+
+// GetServiceRequests получает заявки, подходящие под фильтр
+func (s *DatabaseService) GetServiceRequests(ctx context.Context, filter RequestFilter) ([]*models.ServiceRequest, error) {
+	query := bson.M{}
+	if filter.UserID != 0 {
+		query["user_id"] = filter.UserID
+	}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if !filter.AppointmentFrom.IsZero() {
+		query["appointment_date"] = bson.M{"$gte": filter.AppointmentFrom}
+	}
+	return s.findServiceRequests(ctx, query)
+}
+
+func (s *DatabaseService) findServiceRequests(ctx context.Context, filter bson.M) ([]*models.ServiceRequest, error) {
 	cursor, err := s.requests.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,953 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"volvomaster/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryStore хранит данные в памяти процесса. Используется в тестах и для запуска без MongoDB;
+// после перезапуска данные теряются.
+//
+// Записи хранятся копиями, полученными через BSON, поэтому вызывающий код видит те же значения,
+// что вернула бы MongoDB (время с точностью до миллисекунд, вложенные документы как primitive.D).
+type MemoryStore struct {
+	mu            sync.Mutex
+	users         map[int64]*models.User
+	sessions      map[int64]*models.UserSession
+	requests      map[primitive.ObjectID]*models.ServiceRequest
+	dates         map[primitive.ObjectID]*models.AvailableDate
+	vehicles      map[primitive.ObjectID]*models.Vehicle
+	volvoModels   map[string]*models.VolvoModel
+	admins        map[primitive.ObjectID]*models.Admin
+	adminSessions map[string]*models.AdminSession
+	adminLogins   map[string]*models.AdminLoginCode
+	userLocks     map[int64]userLock
+}
+
+// userLock блокировка клиента в MemoryStore
+type userLock struct {
+	owner     string
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         make(map[int64]*models.User),
+		sessions:      make(map[int64]*models.UserSession),
+		requests:      make(map[primitive.ObjectID]*models.ServiceRequest),
+		dates:         make(map[primitive.ObjectID]*models.AvailableDate),
+		vehicles:      make(map[primitive.ObjectID]*models.Vehicle),
+		volvoModels:   make(map[string]*models.VolvoModel),
+		admins:        make(map[primitive.ObjectID]*models.Admin),
+		adminSessions: make(map[string]*models.AdminSession),
+		adminLogins:   make(map[string]*models.AdminLoginCode),
+		userLocks:     make(map[int64]userLock),
+	}
+}
+
+// copyDocument копирует src в dst так, как если бы документ был сохранен в MongoDB и прочитан обратно
+func copyDocument(src, dst interface{}) error {
+	data, err := bson.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, dst)
+}
+
+// User methods
+func (m *MemoryStore) SaveUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existingUser, ok := m.users[user.UserID]; ok {
+		user.ID = existingUser.ID
+		user.CreatedAt = existingUser.CreatedAt
+
+		// Контакты и имя из заявки приходят не с каждым сообщением, не затираем сохраненные
+		if user.Phone == "" {
+			user.Phone = existingUser.Phone
+		}
+		if user.Telegram == "" {
+			user.Telegram = existingUser.Telegram
+		}
+		if user.Name == "" {
+			user.Name = existingUser.Name
+		}
+	} else {
+		user.ID = primitive.NewObjectID()
+		user.CreatedAt = time.Now()
+	}
+	user.UpdatedAt = time.Now()
+
+	var stored models.User
+	if err := copyDocument(user, &stored); err != nil {
+		return err
+	}
+	m.users[user.UserID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var user models.User
+	if err := copyDocument(stored, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// AvailableDate methods
+func (m *MemoryStore) SaveAvailableDate(ctx context.Context, date *models.AvailableDate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if date.ID.IsZero() {
+		date.ID = primitive.NewObjectID()
+		date.CreatedAt = time.Now()
+	}
+	date.UpdatedAt = time.Now()
+
+	var stored models.AvailableDate
+	if err := copyDocument(date, &stored); err != nil {
+		return err
+	}
+	m.dates[date.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) GetAvailableDates(ctx context.Context) ([]*models.AvailableDate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	today := time.Now().Truncate(24 * time.Hour)
+
+	var dates []*models.AvailableDate
+	for _, stored := range m.dates {
+		if !stored.IsActive || stored.Date.Before(today) {
+			continue
+		}
+		var date models.AvailableDate
+		if err := copyDocument(stored, &date); err != nil {
+			return nil, err
+		}
+		dates = append(dates, &date)
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Date.Before(dates[j].Date)
+	})
+	return dates, nil
+}
+
+func (m *MemoryStore) GetAvailableDateByID(ctx context.Context, id primitive.ObjectID) (*models.AvailableDate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.dates[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var date models.AvailableDate
+	if err := copyDocument(stored, &date); err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// ReserveTimeSlot бронирует слот за заявкой, если он свободен или уже принадлежит этой заявке
+func (m *MemoryStore) ReserveTimeSlot(ctx context.Context, dateID primitive.ObjectID, slotTime string, requestID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	date, ok := m.dates[dateID]
+	if !ok || !date.IsActive {
+		return ErrSlotUnavailable
+	}
+
+	for i := range date.TimeSlots {
+		slot := &date.TimeSlots[i]
+		if slot.Time != slotTime || (slot.IsBooked && slot.RequestID != requestID) {
+			continue
+		}
+		slot.IsBooked = true
+		slot.RequestID = requestID
+		date.UpdatedAt = time.Now()
+		return nil
+	}
+	return ErrSlotUnavailable
+}
+
+// ReleaseTimeSlot освобождает слот, если он забронирован указанной заявкой
+func (m *MemoryStore) ReleaseTimeSlot(ctx context.Context, dateID primitive.ObjectID, slotTime string, requestID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	date, ok := m.dates[dateID]
+	if !ok {
+		return nil
+	}
+
+	for i := range date.TimeSlots {
+		slot := &date.TimeSlots[i]
+		if slot.Time != slotTime || slot.RequestID != requestID {
+			continue
+		}
+		slot.IsBooked = false
+		slot.RequestID = primitive.NilObjectID
+		date.UpdatedAt = time.Now()
+		return nil
+	}
+	return nil
+}
+
+// SetTimeSlotBooked вручную закрывает или открывает слот, если он не забронирован заявкой
+func (m *MemoryStore) SetTimeSlotBooked(ctx context.Context, dateID primitive.ObjectID, index int, booked bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	date, ok := m.dates[dateID]
+	if !ok || index < 0 || index >= len(date.TimeSlots) || !date.TimeSlots[index].RequestID.IsZero() {
+		return ErrSlotUnavailable
+	}
+
+	date.TimeSlots[index].IsBooked = booked
+	date.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetAvailableDateActive включает или отключает дату для записи, не трогая ее слоты
+func (m *MemoryStore) SetAvailableDateActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	date, ok := m.dates[id]
+	if !ok {
+		return ErrNotFound
+	}
+	date.IsActive = active
+	date.UpdatedAt = time.Now()
+	return nil
+}
+
+// ServiceRequest methods
+func (m *MemoryStore) SaveServiceRequest(ctx context.Context, request *models.ServiceRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.requests[request.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	request.UpdatedAt = time.Now()
+
+	// Как и в MongoDB, переписываем только поля requestOwnFields
+	set, unset, err := requestOwnUpdate(request)
+	if err != nil {
+		return err
+	}
+	var doc bson.M
+	if err := copyDocument(stored, &doc); err != nil {
+		return err
+	}
+	for field, value := range set {
+		doc[field] = value
+	}
+	for field := range unset {
+		delete(doc, field)
+	}
+
+	var updated models.ServiceRequest
+	if err := copyDocument(doc, &updated); err != nil {
+		return err
+	}
+	m.requests[request.ID] = &updated
+	return nil
+}
+
+func (m *MemoryStore) GetServiceRequest(ctx context.Context, id primitive.ObjectID) (*models.ServiceRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.requests[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var request models.ServiceRequest
+	if err := copyDocument(stored, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetServiceRequestByUserID получает черновик заявки пользователя
+func (m *MemoryStore) GetServiceRequestByUserID(ctx context.Context, userID int64) (*models.ServiceRequest, error) {
+	requests, err := m.findServiceRequests(func(r *models.ServiceRequest) bool {
+		return r.UserID == userID && r.Status == models.StatusDraft
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, ErrNotFound
+	}
+	return requests[0], nil
+}
+
+// GetServiceRequests получает заявки, подходящие под фильтр, в порядке создания
+func (m *MemoryStore) GetServiceRequests(ctx context.Context, filter RequestFilter) ([]*models.ServiceRequest, error) {
+	return m.findServiceRequests(func(r *models.ServiceRequest) bool {
+		if filter.UserID != 0 && r.UserID != filter.UserID {
+			return false
+		}
+		if len(filter.Statuses) > 0 && !containsString(filter.Statuses, r.Status) {
+			return false
+		}
+		if !filter.AppointmentFrom.IsZero() && r.AppointmentDate.Before(filter.AppointmentFrom) {
+			return false
+		}
+		return true
+	})
+}
+
+func (m *MemoryStore) findServiceRequests(match func(*models.ServiceRequest) bool) ([]*models.ServiceRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var requests []*models.ServiceRequest
+	for _, stored := range m.requests {
+		if !match(stored) {
+			continue
+		}
+		var request models.ServiceRequest
+		if err := copyDocument(stored, &request); err != nil {
+			return nil, err
+		}
+		requests = append(requests, &request)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+	return requests, nil
+}
+
+// AddServiceRequestNote добавляет внутреннюю заметку к заявке
+func (m *MemoryStore) AddServiceRequestNote(ctx context.Context, requestID primitive.ObjectID, note models.RequestNote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	request, ok := m.requests[requestID]
+	if !ok {
+		return ErrNotFound
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+
+	request.Notes = append(request.Notes, note)
+	request.UpdatedAt = time.Now()
+	return nil
+}
+
+// AddServiceRequestAttachment добавляет вложение к заявке
+func (m *MemoryStore) AddServiceRequestAttachment(ctx context.Context, requestID primitive.ObjectID, attachment models.Attachment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	request, ok := m.requests[requestID]
+	if !ok {
+		return ErrNotFound
+	}
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+
+	request.Attachments = append(request.Attachments, attachment)
+	request.UpdatedAt = time.Now()
+	return nil
+}
+
+// GetRequestsDueForReminder получает записанные заявки со временем записи в интервале (from, to],
+// для которых напоминание указанного вида еще не отправлялось
+func (m *MemoryStore) GetRequestsDueForReminder(ctx context.Context, kind string, from, to time.Time) ([]*models.ServiceRequest, error) {
+	return m.findServiceRequests(func(r *models.ServiceRequest) bool {
+		return (r.Status == models.StatusBooked || r.Status == models.StatusConfirmed) &&
+			r.AppointmentDate.After(from) && !r.AppointmentDate.After(to) &&
+			!containsString(r.RemindersSent, kind)
+	})
+}
+
+// MarkReminderSent отмечает напоминание отправленным.
+// Возвращает false, если напоминание уже было отмечено ранее.
+func (m *MemoryStore) MarkReminderSent(ctx context.Context, requestID primitive.ObjectID, kind string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	request, ok := m.requests[requestID]
+	if !ok || containsString(request.RemindersSent, kind) {
+		return false, nil
+	}
+	request.RemindersSent = append(request.RemindersSent, kind)
+	return true, nil
+}
+
+// UnmarkReminderSent снимает отметку об отправке, чтобы напоминание было отправлено повторно
+func (m *MemoryStore) UnmarkReminderSent(ctx context.Context, requestID primitive.ObjectID, kind string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	request, ok := m.requests[requestID]
+	if !ok {
+		return nil
+	}
+
+	kept := request.RemindersSent[:0]
+	for _, sent := range request.RemindersSent {
+		if sent != kind {
+			kept = append(kept, sent)
+		}
+	}
+	request.RemindersSent = kept
+	return nil
+}
+
+// TransitionServiceRequest переводит заявку в новый статус и дописывает историю,
+// если сохраненный статус все еще тот, из которого проверялся переход
+func (m *MemoryStore) TransitionServiceRequest(ctx context.Context, request *models.ServiceRequest, to, actor, reason string) error {
+	change, err := newStatusChange(request, to, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Новая заявка создается сразу в статусе draft
+	if change.From == "" {
+		created := *request
+		applyStatusChange(&created, change)
+		created.ID = primitive.NewObjectID()
+		created.CreatedAt = change.At
+
+		var stored models.ServiceRequest
+		if err := copyDocument(&created, &stored); err != nil {
+			return err
+		}
+		m.requests[created.ID] = &stored
+		*request = created
+		return nil
+	}
+
+	return m.swapStatus(request, change, nil)
+}
+
+// BookServiceRequest переводит заявку в статус booked и одновременно записывает выбранное время
+func (m *MemoryStore) BookServiceRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error {
+	change, err := newStatusChange(request, models.StatusBooked, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err = m.swapStatus(request, change, func(stored *models.ServiceRequest) {
+		stored.AppointmentDate = request.AppointmentDate
+		stored.AvailableDateID = request.AvailableDateID
+		stored.TimeSlot = request.TimeSlot
+		stored.Stage = request.Stage
+		stored.RemindersSent = nil
+	})
+	if err == nil {
+		request.RemindersSent = nil
+	}
+	return err
+}
+
+// swapStatus записывает переход, если сохраненный статус заявки равен change.From.
+// update дополнительно меняет сохраненную заявку. Вызывается под m.mu.
+func (m *MemoryStore) swapStatus(request *models.ServiceRequest, change models.StatusChange, update func(*models.ServiceRequest)) error {
+	stored, ok := m.requests[request.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Status != change.From {
+		return ErrStatusChanged
+	}
+
+	applyStatusChange(stored, change)
+	if update != nil {
+		update(stored)
+	}
+
+	// Приводим значения к виду, в котором их вернула бы MongoDB
+	var updated models.ServiceRequest
+	if err := copyDocument(stored, &updated); err != nil {
+		return err
+	}
+	m.requests[request.ID] = &updated
+
+	applyStatusChange(request, change)
+	return nil
+}
+
+// CancelServiceRequest переводит заявку в статус cancelled и освобождает занятый ею слот
+func (m *MemoryStore) CancelServiceRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error {
+	return cancelServiceRequest(ctx, m, request, actor, reason)
+}
+
+// UserSession methods
+func (m *MemoryStore) SaveUserSession(ctx context.Context, session *models.UserSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session.UpdatedAt = time.Now()
+
+	var stored models.UserSession
+	if err := copyDocument(session, &stored); err != nil {
+		return err
+	}
+	m.sessions[session.UserID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) GetUserSession(ctx context.Context, userID int64) (*models.UserSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.sessions[userID]
+	if !ok {
+		// Создаем новую сессию
+		return &models.UserSession{
+			UserID:    userID,
+			Stage:     models.StageStart,
+			Data:      make(map[string]interface{}),
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	var session models.UserSession
+	if err := copyDocument(stored, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (m *MemoryStore) DeleteUserSession(ctx context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, userID)
+	return nil
+}
+
+// GetIdleSessions возвращает сессии на указанных этапах, которые не обновлялись с before
+func (m *MemoryStore) GetIdleSessions(ctx context.Context, stages []int, before time.Time) ([]*models.UserSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sessions []*models.UserSession
+	for _, stored := range m.sessions {
+		if stored.UpdatedAt.After(before) || !containsInt(stages, stored.Stage) {
+			continue
+		}
+		var session models.UserSession
+		if err := copyDocument(stored, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
+
+// MarkSessionNudged отмечает, что клиенту предложено продолжить заявку.
+// Возвращает false, если отметка уже есть или сессия обновилась после before.
+func (m *MemoryStore) MarkSessionNudged(ctx context.Context, userID int64, before time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[userID]
+	if !ok || session.UpdatedAt.After(before) || session.NudgedAt != nil {
+		return false, nil
+	}
+	// updated_at не меняем, чтобы отметка не продлевала срок жизни сессии
+	now := time.Now()
+	session.NudgedAt = &now
+	return true, nil
+}
+
+// AcquireUserLock захватывает блокировку клиента, если она свободна, истекла или уже принадлежит owner
+func (m *MemoryStore) AcquireUserLock(ctx context.Context, userID int64, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if lock, ok := m.userLocks[userID]; ok && lock.owner != owner && lock.expiresAt.After(now) {
+		return false, nil
+	}
+	m.userLocks[userID] = userLock{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// ReleaseUserLock снимает блокировку клиента, если она принадлежит owner
+func (m *MemoryStore) ReleaseUserLock(ctx context.Context, userID int64, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lock, ok := m.userLocks[userID]; ok && lock.owner == owner {
+		delete(m.userLocks, userID)
+	}
+	return nil
+}
+
+// ExpireUserSession удаляет сессию, если она не обновлялась с before.
+// Возвращает false, если клиент успел продолжить заполнение.
+func (m *MemoryStore) ExpireUserSession(ctx context.Context, userID int64, before time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[userID]
+	if !ok || session.UpdatedAt.After(before) {
+		return false, nil
+	}
+	delete(m.sessions, userID)
+	return true, nil
+}
+
+// Vehicle methods
+func (m *MemoryStore) SaveVehicle(ctx context.Context, vehicle *models.Vehicle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if vehicle.ID.IsZero() {
+		vehicle.ID = primitive.NewObjectID()
+		vehicle.CreatedAt = time.Now()
+	}
+	vehicle.UpdatedAt = time.Now()
+
+	var stored models.Vehicle
+	if err := copyDocument(vehicle, &stored); err != nil {
+		return err
+	}
+	m.vehicles[vehicle.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) GetVehicle(ctx context.Context, id primitive.ObjectID) (*models.Vehicle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.vehicles[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var vehicle models.Vehicle
+	if err := copyDocument(stored, &vehicle); err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// GetUserVehicles получает автомобили клиента, начиная с последнего обслуживавшегося
+func (m *MemoryStore) GetUserVehicles(ctx context.Context, userID int64) ([]*models.Vehicle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var vehicles []*models.Vehicle
+	for _, stored := range m.vehicles {
+		if stored.UserID != userID {
+			continue
+		}
+		var vehicle models.Vehicle
+		if err := copyDocument(stored, &vehicle); err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, &vehicle)
+	}
+
+	sort.Slice(vehicles, func(i, j int) bool {
+		return vehicles[i].UpdatedAt.After(vehicles[j].UpdatedAt)
+	})
+	return vehicles, nil
+}
+
+// Catalog methods
+
+// SeedVolvoModels добавляет в каталог недостающие модели, существующие не перезаписывает
+func (m *MemoryStore) SeedVolvoModels(ctx context.Context, catalog []models.VolvoModel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, model := range catalog {
+		if _, ok := m.volvoModels[model.ID]; ok {
+			continue
+		}
+		var stored models.VolvoModel
+		if err := copyDocument(model, &stored); err != nil {
+			return err
+		}
+		m.volvoModels[model.ID] = &stored
+	}
+	return nil
+}
+
+// GetVolvoModels получает каталог моделей, отсортированный по названию
+func (m *MemoryStore) GetVolvoModels(ctx context.Context) ([]models.VolvoModel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var catalog []models.VolvoModel
+	for _, stored := range m.volvoModels {
+		var model models.VolvoModel
+		if err := copyDocument(stored, &model); err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, model)
+	}
+
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Name < catalog[j].Name
+	})
+	return catalog, nil
+}
+
+// GetVolvoModel получает модель из каталога по ID
+func (m *MemoryStore) GetVolvoModel(ctx context.Context, id string) (*models.VolvoModel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.volvoModels[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var model models.VolvoModel
+	if err := copyDocument(stored, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Admin methods
+
+// CreateAdmin создает сотрудника с хешированным паролем
+func (m *MemoryStore) CreateAdmin(ctx context.Context, username, password, role string) (*models.Admin, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.findAdmin(func(a *models.Admin) bool { return a.Username == username }) != nil {
+		return nil, ErrAdminExists
+	}
+
+	admin := &models.Admin{
+		ID:           primitive.NewObjectID(),
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         role,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	return admin, m.storeAdmin(admin)
+}
+
+func (m *MemoryStore) GetAdmins(ctx context.Context) ([]*models.Admin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var admins []*models.Admin
+	for _, stored := range m.admins {
+		var admin models.Admin
+		if err := copyDocument(stored, &admin); err != nil {
+			return nil, err
+		}
+		admins = append(admins, &admin)
+	}
+
+	sort.Slice(admins, func(i, j int) bool {
+		return admins[i].Username < admins[j].Username
+	})
+	return admins, nil
+}
+
+func (m *MemoryStore) CountAdmins(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return int64(len(m.admins)), nil
+}
+
+// AuthenticateAdmin проверяет логин и пароль сотрудника
+func (m *MemoryStore) AuthenticateAdmin(ctx context.Context, username, password string) (*models.Admin, error) {
+	m.mu.Lock()
+	admin := m.findAdmin(func(a *models.Admin) bool { return a.Username == username })
+	m.mu.Unlock()
+
+	if admin == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return admin, nil
+}
+
+// GetOrCreateTelegramAdmin находит сотрудника по Telegram ID или создает сотрудника без пароля с ролью role
+func (m *MemoryStore) GetOrCreateTelegramAdmin(ctx context.Context, telegramID int64, role string) (*models.Admin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if admin := m.findAdmin(func(a *models.Admin) bool { return a.TelegramID == telegramID }); admin != nil {
+		return admin, nil
+	}
+
+	admin := newTelegramAdmin(telegramID, role)
+	return admin, m.storeAdmin(admin)
+}
+
+// findAdmin возвращает копию первого подходящего сотрудника или nil. Вызывается под m.mu.
+func (m *MemoryStore) findAdmin(match func(*models.Admin) bool) *models.Admin {
+	for _, stored := range m.admins {
+		if !match(stored) {
+			continue
+		}
+		var admin models.Admin
+		if err := copyDocument(stored, &admin); err != nil {
+			return nil
+		}
+		return &admin
+	}
+	return nil
+}
+
+// storeAdmin сохраняет копию сотрудника. Вызывается под m.mu.
+func (m *MemoryStore) storeAdmin(admin *models.Admin) error {
+	var stored models.Admin
+	if err := copyDocument(admin, &stored); err != nil {
+		return err
+	}
+	m.admins[admin.ID] = &stored
+	return nil
+}
+
+// CreateAdminSession создает сессию входа со случайными токенами сессии и CSRF
+func (m *MemoryStore) CreateAdminSession(ctx context.Context, admin *models.Admin) (*models.AdminSession, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.AdminSession{
+		Token:     token,
+		AdminID:   admin.ID,
+		Username:  admin.Username,
+		Role:      admin.Role,
+		CSRFToken: csrfToken,
+		ExpiresAt: time.Now().Add(AdminSessionTTL),
+		CreatedAt: time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *session
+	m.adminSessions[token] = &stored
+	return session, nil
+}
+
+// GetAdminSession получает действующую сессию по токену
+func (m *MemoryStore) GetAdminSession(ctx context.Context, token string) (*models.AdminSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.adminSessions[token]
+	if !ok || !stored.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	session := *stored
+	return &session, nil
+}
+
+func (m *MemoryStore) DeleteAdminSession(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.adminSessions, token)
+	return nil
+}
+
+// CreateAdminLoginCode выдает одноразовый код входа для сотрудника Telegram.
+// Хранится только хеш кода.
+func (m *MemoryStore) CreateAdminLoginCode(ctx context.Context, telegramID int64, username string) (string, error) {
+	code, err := newLoginCode()
+	if err != nil {
+		return "", err
+	}
+
+	login := &models.AdminLoginCode{
+		CodeHash:   hashLoginCode(code),
+		TelegramID: telegramID,
+		Username:   username,
+		ExpiresAt:  time.Now().Add(AdminLoginCodeTTL),
+		CreatedAt:  time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.adminLogins[login.CodeHash] = login
+	return code, nil
+}
+
+// ConsumeAdminLoginCode проверяет и удаляет код входа, чтобы его нельзя было использовать повторно
+func (m *MemoryStore) ConsumeAdminLoginCode(ctx context.Context, code string) (*models.AdminLoginCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := hashLoginCode(normalizeLoginCode(code))
+	login, ok := m.adminLogins[hash]
+	if !ok || !login.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidLoginCode
+	}
+	delete(m.adminLogins, hash)
+	return login, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
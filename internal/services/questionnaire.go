@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"volvomaster/internal/questionnaire"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetQuestionnaire загружает анкету из коллекции questionnaires и проверяет ее корректность
+func (s *DatabaseService) GetQuestionnaire(ctx context.Context, id string) (*questionnaire.Definition, error) {
+	var def questionnaire.Definition
+	err := findOne(ctx, s.questionnaires, bson.M{"_id": id}, &def)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("анкета %s некорректна: %w", id, err)
+	}
+	return &def, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"volvomaster/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound возвращается любым хранилищем, если запись не найдена
+var ErrNotFound = errors.New("запись не найдена")
+
+// UserRepository пользователи бота
+type UserRepository interface {
+	SaveUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, userID int64) (*models.User, error)
+}
+
+// SessionRepository сессии пользователей бота
+type SessionRepository interface {
+	SaveUserSession(ctx context.Context, session *models.UserSession) error
+	// GetUserSession возвращает новую сессию на этапе StageStart, если сохраненной нет
+	GetUserSession(ctx context.Context, userID int64) (*models.UserSession, error)
+	DeleteUserSession(ctx context.Context, userID int64) error
+	GetIdleSessions(ctx context.Context, stages []int, before time.Time) ([]*models.UserSession, error)
+	MarkSessionNudged(ctx context.Context, userID int64, before time.Time) (bool, error)
+	ExpireUserSession(ctx context.Context, userID int64, before time.Time) (bool, error)
+}
+
+// RequestFilter условия выборки заявок; пустые поля выборку не ограничивают
+type RequestFilter struct {
+	UserID          int64
+	Statuses        []string
+	AppointmentFrom time.Time // время записи не раньше указанного
+}
+
+// RequestRepository заявки на обслуживание
+type RequestRepository interface {
+	SaveServiceRequest(ctx context.Context, request *models.ServiceRequest) error
+	GetServiceRequest(ctx context.Context, id primitive.ObjectID) (*models.ServiceRequest, error)
+	GetServiceRequestByUserID(ctx context.Context, userID int64) (*models.ServiceRequest, error)
+	GetServiceRequests(ctx context.Context, filter RequestFilter) ([]*models.ServiceRequest, error)
+	AddServiceRequestNote(ctx context.Context, requestID primitive.ObjectID, note models.RequestNote) error
+	AddServiceRequestAttachment(ctx context.Context, requestID primitive.ObjectID, attachment models.Attachment) error
+
+	GetRequestsDueForReminder(ctx context.Context, kind string, from, to time.Time) ([]*models.ServiceRequest, error)
+	MarkReminderSent(ctx context.Context, requestID primitive.ObjectID, kind string) (bool, error)
+	UnmarkReminderSent(ctx context.Context, requestID primitive.ObjectID, kind string) error
+
+	TransitionServiceRequest(ctx context.Context, request *models.ServiceRequest, to, actor, reason string) error
+	BookServiceRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error
+	CancelServiceRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error
+}
+
+// DateRepository даты и временные слоты для записи
+type DateRepository interface {
+	SaveAvailableDate(ctx context.Context, date *models.AvailableDate) error
+	GetAvailableDates(ctx context.Context) ([]*models.AvailableDate, error)
+	GetAvailableDateByID(ctx context.Context, id primitive.ObjectID) (*models.AvailableDate, error)
+	ReserveTimeSlot(ctx context.Context, dateID primitive.ObjectID, slotTime string, requestID primitive.ObjectID) error
+	ReleaseTimeSlot(ctx context.Context, dateID primitive.ObjectID, slotTime string, requestID primitive.ObjectID) error
+	SetTimeSlotBooked(ctx context.Context, dateID primitive.ObjectID, index int, booked bool) error
+	SetAvailableDateActive(ctx context.Context, id primitive.ObjectID, active bool) error
+}
+
+// VehicleRepository гараж клиентов
+type VehicleRepository interface {
+	SaveVehicle(ctx context.Context, vehicle *models.Vehicle) error
+	GetVehicle(ctx context.Context, id primitive.ObjectID) (*models.Vehicle, error)
+	GetUserVehicles(ctx context.Context, userID int64) ([]*models.Vehicle, error)
+}
+
+// CatalogRepository каталог моделей Volvo
+type CatalogRepository interface {
+	SeedVolvoModels(ctx context.Context, catalog []models.VolvoModel) error
+	GetVolvoModels(ctx context.Context) ([]models.VolvoModel, error)
+	GetVolvoModel(ctx context.Context, id string) (*models.VolvoModel, error)
+}
+
+// AdminRepository сотрудники, их сессии и коды входа в админ-панель
+type AdminRepository interface {
+	CreateAdmin(ctx context.Context, username, password, role string) (*models.Admin, error)
+	GetAdmins(ctx context.Context) ([]*models.Admin, error)
+	CountAdmins(ctx context.Context) (int64, error)
+	AuthenticateAdmin(ctx context.Context, username, password string) (*models.Admin, error)
+	GetOrCreateTelegramAdmin(ctx context.Context, telegramID int64, role string) (*models.Admin, error)
+
+	CreateAdminSession(ctx context.Context, admin *models.Admin) (*models.AdminSession, error)
+	GetAdminSession(ctx context.Context, token string) (*models.AdminSession, error)
+	DeleteAdminSession(ctx context.Context, token string) error
+
+	CreateAdminLoginCode(ctx context.Context, telegramID int64, username string) (string, error)
+	ConsumeAdminLoginCode(ctx context.Context, code string) (*models.AdminLoginCode, error)
+}
+
+// LockRepository блокировки клиентов, общие для всех экземпляров бота.
+// Обновления клиента обрабатывает только экземпляр, владеющий его блокировкой.
+type LockRepository interface {
+	// AcquireUserLock захватывает или продлевает блокировку клиента для owner на ttl.
+	// Возвращает false, если неистекшая блокировка принадлежит другому владельцу.
+	AcquireUserLock(ctx context.Context, userID int64, owner string, ttl time.Duration) (bool, error)
+	// ReleaseUserLock снимает блокировку, если она принадлежит owner
+	ReleaseUserLock(ctx context.Context, userID int64, owner string) error
+}
+
+// Store все данные, с которыми работают бот и админ-панель.
+// Реализации: DatabaseService (MongoDB) и MemoryStore (в памяти, для тестов и запуска без MongoDB).
+type Store interface {
+	UserRepository
+	SessionRepository
+	RequestRepository
+	DateRepository
+	VehicleRepository
+	CatalogRepository
+	AdminRepository
+	LockRepository
+}
+
+var (
+	_ Store = (*DatabaseService)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
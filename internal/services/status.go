@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"volvomaster/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidTransition возвращается при попытке недопустимого перехода статуса
+var ErrInvalidTransition = errors.New("недопустимый переход статуса")
+
+// ErrStatusChanged возвращается, если статус заявки изменил кто-то другой после того, как она была прочитана.
+// Это частный случай ErrInvalidTransition: переход проверялся для статуса, которого у заявки уже нет.
+var ErrStatusChanged = fmt.Errorf("%w: статус заявки изменился", ErrInvalidTransition)
+
+// SystemActor автор переходов, выполняемых автоматически
+const SystemActor = "system"
+
+// statusTransitions допустимые переходы статусов заявки.
+// Переход booked → booked и confirmed → booked означает перенос записи.
+var statusTransitions = map[string][]string{
+	"":                     {models.StatusDraft},
+	models.StatusDraft:     {models.StatusBooked, models.StatusCancelled},
+	models.StatusBooked:    {models.StatusBooked, models.StatusConfirmed, models.StatusInService, models.StatusCancelled, models.StatusNoShow},
+	models.StatusConfirmed: {models.StatusBooked, models.StatusInService, models.StatusCancelled, models.StatusNoShow},
+	models.StatusInService: {models.StatusDone, models.StatusCancelled},
+}
+
+// CustomerActor возвращает автора перехода для клиента бота
+func CustomerActor(userID int64) string {
+	return fmt.Sprintf("customer:%d", userID)
+}
+
+// CanTransition проверяет, допустим ли переход из статуса from в статус to
+func CanTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTransitions возвращает статусы, в которые можно перевести заявку из статуса from
+func AllowedTransitions(from string) []string {
+	return statusTransitions[from]
+}
+
+// TransitionServiceRequest переводит заявку в новый статус и дописывает историю.
+// Статус меняется, только если в базе он все еще тот, из которого проверялся переход,
+// иначе возвращается ErrStatusChanged. Остальные поля заявки не записываются.
+func (s *DatabaseService) TransitionServiceRequest(ctx context.Context, request *models.ServiceRequest, to, actor, reason string) error {
+	change, err := newStatusChange(request, to, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	// Новая заявка создается сразу в статусе draft
+	if change.From == "" {
+		created := *request
+		applyStatusChange(&created, change)
+		created.ID = primitive.NewObjectID()
+		created.CreatedAt = change.At
+		if _, err := s.requests.InsertOne(ctx, &created); err != nil {
+			return err
+		}
+		*request = created
+		return nil
+	}
+
+	return s.swapStatus(ctx, request, change, nil)
+}
+
+// BookServiceRequest переводит заявку в статус booked и одновременно записывает выбранное время:
+// дату, слот, этап заявки. Отметки об отправленных напоминаниях сбрасываются.
+// Как и TransitionServiceRequest, не перезаписывает статус, измененный кем-то другим.
+func (s *DatabaseService) BookServiceRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error {
+	change, err := newStatusChange(request, models.StatusBooked, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	err = s.swapStatus(ctx, request, change, bson.M{
+		"appointment_date":  request.AppointmentDate,
+		"available_date_id": request.AvailableDateID,
+		"time_slot":         request.TimeSlot,
+		"stage":             request.Stage,
+		"reminders_sent":    bson.A{},
+	})
+	if err == nil {
+		request.RemindersSent = nil
+	}
+	return err
+}
+
+// swapStatus записывает переход, если статус заявки в базе равен change.From.
+// fields дополнительно записываются тем же запросом.
+func (s *DatabaseService) swapStatus(ctx context.Context, request *models.ServiceRequest, change models.StatusChange, fields bson.M) error {
+	set := bson.M{"status": change.To, "updated_at": change.At}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	filter := bson.M{"_id": request.ID, "status": change.From}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"status_history": change},
+	}
+
+	result, err := s.requests.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrStatusChanged
+	}
+
+	applyStatusChange(request, change)
+	return nil
+}
+
+// CancelServiceRequest переводит заявку в статус cancelled и освобождает занятый ею слот
+func (s *DatabaseService) CancelServiceRequest(ctx context.Context, request *models.ServiceRequest, actor, reason string) error {
+	return cancelServiceRequest(ctx, s, request, actor, reason)
+}
+
+// newStatusChange проверяет переход из текущего статуса заявки и возвращает запись для истории
+func newStatusChange(request *models.ServiceRequest, to, actor, reason string) (models.StatusChange, error) {
+	from := request.Status
+	if !CanTransition(from, to) {
+		return models.StatusChange{}, fmt.Errorf("%w: %q → %q", ErrInvalidTransition, from, to)
+	}
+
+	return models.StatusChange{
+		From:   from,
+		To:     to,
+		Actor:  actor,
+		Reason: reason,
+		At:     time.Now(),
+	}, nil
+}
+
+// applyStatusChange отражает записанный переход в заявке вызывающего кода
+func applyStatusChange(request *models.ServiceRequest, change models.StatusChange) {
+	request.Status = change.To
+	request.StatusHistory = append(request.StatusHistory, change)
+	request.UpdatedAt = change.At
+}
+
+// cancelServiceRequest общая для всех хранилищ отмена заявки
+func cancelServiceRequest(ctx context.Context, store Store, request *models.ServiceRequest, actor, reason string) error {
+	if err := store.TransitionServiceRequest(ctx, request, models.StatusCancelled, actor, reason); err != nil {
+		return err
+	}
+
+	if request.AvailableDateID.IsZero() {
+		return nil
+	}
+	return store.ReleaseTimeSlot(ctx, request.AvailableDateID, request.TimeSlot, request.ID)
+}
+
+// MigrateLegacyStatuses переводит заявки со старыми статусами на новый жизненный цикл
+func (s *DatabaseService) MigrateLegacyStatuses(ctx context.Context) error {
+	migrations := []struct {
+		filter bson.M
+		status string
+	}{
+		{bson.M{"status": "in_progress"}, models.StatusDraft},
+		{bson.M{"status": "completed", "confirmation": "confirmed"}, models.StatusConfirmed},
+		{bson.M{"status": "completed"}, models.StatusBooked},
+	}
+
+	for _, m := range migrations {
+		update := bson.M{
+			"$set": bson.M{"status": m.status},
+			"$push": bson.M{"status_history": models.StatusChange{
+				From:   m.filter["status"].(string),
+				To:     m.status,
+				Actor:  SystemActor,
+				Reason: "миграция статусов",
+				At:     time.Now(),
+			}},
+			"$unset": bson.M{"confirmation": "", "confirmation_at": ""},
+		}
+		if _, err := s.requests.UpdateMany(ctx, m.filter, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
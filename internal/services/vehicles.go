@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"volvomaster/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveVehicle сохраняет автомобиль клиента
+func (s *DatabaseService) SaveVehicle(ctx context.Context, vehicle *models.Vehicle) error {
+	if vehicle.ID.IsZero() {
+		vehicle.ID = primitive.NewObjectID()
+		vehicle.CreatedAt = time.Now()
+	}
+	vehicle.UpdatedAt = time.Now()
+
+	_, err := s.vehicles.ReplaceOne(ctx, bson.M{"_id": vehicle.ID}, vehicle, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetVehicle получает автомобиль по ID
+func (s *DatabaseService) GetVehicle(ctx context.Context, id primitive.ObjectID) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := findOne(ctx, s.vehicles, bson.M{"_id": id}, &vehicle); err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// GetUserVehicles получает автомобили клиента, начиная с последнего обслуживавшегося
+func (s *DatabaseService) GetUserVehicles(ctx context.Context, userID int64) ([]*models.Vehicle, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+
+	cursor, err := s.vehicles.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var vehicles []*models.Vehicle
+	if err := cursor.All(ctx, &vehicles); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
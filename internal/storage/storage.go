@@ -0,0 +1,44 @@
+// Package storage сохраняет файлы вложений заявок в локальный каталог или S3-совместимое хранилище.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"volvomaster/internal/config"
+)
+
+// ErrNotFound возвращается, если файла нет в хранилище
+var ErrNotFound = errors.New("файл не найден в хранилище")
+
+// BlobStore хранилище файлов
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// New создает хранилище по настройкам. Если хранилище не настроено или настроено с ошибкой, возвращает nil.
+func New(cfg config.StorageConfig) (BlobStore, error) {
+	var (
+		store BlobStore
+		err   error
+	)
+
+	// Ошибку конструктора не возвращаем вместе с хранилищем: интерфейс с nil-указателем внутри не равен nil
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "local":
+		store, err = NewLocalStore(cfg.Dir)
+	case "s3":
+		store, err = NewS3Store(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey)
+	default:
+		err = fmt.Errorf("неизвестное хранилище вложений: %s", cfg.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"testing"
+
+	"volvomaster/internal/config"
+)
+
+func TestNewInvalidConfig(t *testing.T) {
+	tests := map[string]config.StorageConfig{
+		"s3 без ключей":       {Backend: "s3", S3Endpoint: "https://s3.example.com", S3Bucket: "attachments"},
+		"неизвестный backend": {Backend: "ftp"},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := New(cfg)
+			if err == nil {
+				t.Fatal("ожидалась ошибка настройки")
+			}
+			if store != nil {
+				t.Errorf("вместе с ошибкой возвращено хранилище %#v", store)
+			}
+		})
+	}
+}
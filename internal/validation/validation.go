@@ -0,0 +1,203 @@
+// Package validation разбирает и нормализует ответы клиентов: год выпуска,
+// объем двигателя, пробег и контакт. Ошибки содержат текст для пользователя.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FirstVolvoYear год выпуска первого автомобиля Volvo
+const FirstVolvoYear = 1927
+
+const kmPerMile = 1.609344
+
+var (
+	yearPattern     = regexp.MustCompile(`^\d{4}$`)
+	numberPattern   = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)\s*(.*)$`)
+	groupedPattern  = regexp.MustCompile(`^\d{1,3}(?:[.,]\d{3})+`)
+	usernamePattern = regexp.MustCompile(`^@[A-Za-z0-9_]{5,32}$`)
+)
+
+// unknownAnswers ответы, которыми клиент сообщает, что не знает значение
+var unknownAnswers = map[string]bool{
+	"не знаю": true,
+	"незнаю":  true,
+	"нет":     true,
+	"-":       true,
+	"?":       true,
+}
+
+// IsUnknown проверяет, ответил ли клиент, что не знает значение
+func IsUnknown(text string) bool {
+	return unknownAnswers[strings.ToLower(strings.TrimSpace(text))]
+}
+
+// ParseYear разбирает год выпуска и проверяет, что он не раньше первого Volvo и не в будущем
+func ParseYear(text string, now time.Time) (int, error) {
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "г."))
+	text = strings.TrimSpace(strings.TrimSuffix(text, "г"))
+	if !yearPattern.MatchString(text) {
+		return 0, errors.New("Укажите год выпуска четырьмя цифрами, например 2017.")
+	}
+
+	year, _ := strconv.Atoi(text)
+	return year, CheckYear(year, FirstVolvoYear, now.Year()+1)
+}
+
+// CheckYear проверяет, что год выпуска попадает в годы производства [from, to]
+func CheckYear(year, from, to int) error {
+	if year < from || year > to {
+		return fmt.Errorf("Год выпуска должен быть от %d до %d.", from, to)
+	}
+	return nil
+}
+
+// ParseEngineVolume разбирает объем двигателя в литрах или кубических сантиметрах
+// ("2.0", "2,0 л", "1969 см3") и возвращает объем в литрах с точностью до 0.1
+func ParseEngineVolume(text string) (float64, error) {
+	value, unit, ok := splitNumber(text)
+	if !ok {
+		return 0, errors.New("Укажите объем двигателя числом, например 2.0 или 1969.")
+	}
+
+	switch unit {
+	case "", "л", "l", "литра", "литров":
+		// Значения больше 100 - это кубические сантиметры без единиц
+		if value > 100 {
+			value /= 1000
+		}
+	case "см3", "куб", "куб.см", "cc", "cm3":
+		value /= 1000
+	default:
+		return 0, errors.New("Не удалось распознать единицы объема. Укажите объем в литрах, например 2.0.")
+	}
+
+	value = math.Round(value*10) / 10
+	if value < 0.8 || value > 7 {
+		return 0, errors.New("Объем двигателя должен быть от 0.8 до 7.0 литра.")
+	}
+	return value, nil
+}
+
+// ParseMileage разбирает пробег в километрах или милях ("120 000 км", "85.000 км", "85 тыс", "60000 miles")
+// и возвращает пробег в километрах
+func ParseMileage(text string) (int, error) {
+	// Убираем пробелы-разделители разрядов
+	text = strings.NewReplacer(" ", "", " ", "", "'", "").Replace(strings.TrimSpace(text))
+	text = removeThousandsSeparators(text)
+
+	value, unit, ok := splitNumber(text)
+	if !ok {
+		return 0, errors.New("Укажите пробег числом, например 120000 км.")
+	}
+
+	multiplier, ok := mileageMultiplier(unit)
+	if !ok {
+		// "85 тыс км", "120k miles"
+		for _, prefix := range []string{"тыс.", "тыс", "т.", "т", "k", "к"} {
+			if m, found := mileageMultiplier(strings.TrimPrefix(unit, prefix)); strings.HasPrefix(unit, prefix) && found {
+				multiplier, ok = m*1000, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, errors.New("Не удалось распознать единицы пробега. Укажите пробег в километрах или милях.")
+	}
+
+	km := int(math.Round(value * multiplier))
+	if km > 2_000_000 {
+		return 0, errors.New("Пробег выглядит слишком большим. Проверьте, пожалуйста, значение.")
+	}
+	return km, nil
+}
+
+// NormalizePhone приводит номер телефона к формату E.164.
+// Российские номера вида 8XXXXXXXXXX и 7XXXXXXXXXX дополняются кодом +7.
+func NormalizePhone(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	international := strings.HasPrefix(text, "+")
+
+	var digits strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune("+ -().", r):
+		default:
+			return "", errors.New("Номер телефона может содержать только цифры, например +7 912 345-67-89.")
+		}
+	}
+	number := digits.String()
+
+	if !international && len(number) == 11 && (number[0] == '8' || number[0] == '7') {
+		number = "7" + number[1:]
+		international = true
+	}
+	if !international && len(number) == 10 && number[0] == '9' {
+		number = "7" + number
+		international = true
+	}
+
+	if !international || len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", errors.New("Укажите номер телефона в международном формате, например +7 912 345-67-89.")
+	}
+	return "+" + number, nil
+}
+
+// ParseContact разбирает контакт для связи: номер телефона или @username в Telegram.
+// Для телефона возвращает номер в формате E.164, для username - пустую строку.
+func ParseContact(text string) (phone string, err error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "@") {
+		if !usernamePattern.MatchString(text) {
+			return "", errors.New("Username в Telegram должен начинаться с @ и содержать от 5 до 32 латинских букв, цифр или _.")
+		}
+		return "", nil
+	}
+	return NormalizePhone(text)
+}
+
+// splitNumber отделяет число от единиц измерения
+func splitNumber(text string) (float64, string, bool) {
+	match := numberPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(text)))
+	if match == nil {
+		return 0, "", false
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return value, strings.TrimSpace(match[2]), true
+}
+
+// removeThousandsSeparators убирает точки и запятые между группами по три цифры в начале text:
+// "120,000miles" -> "120000miles", "1.200.000км" -> "1200000км". Дробная часть ("85.5тыс") не меняется.
+func removeThousandsSeparators(text string) string {
+	grouped := groupedPattern.FindString(text)
+	if grouped == "" {
+		return text
+	}
+	if rest := text[len(grouped):]; rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+		return text
+	}
+	return strings.NewReplacer(".", "", ",", "").Replace(grouped) + text[len(grouped):]
+}
+
+// mileageMultiplier возвращает множитель для перевода пробега в километры
+func mileageMultiplier(unit string) (float64, bool) {
+	switch unit {
+	case "", "км", "km", "километров":
+		return 1, true
+	case "миль", "мили", "mi", "miles", "mile":
+		return kmPerMile, true
+	}
+	return 0, false
+}
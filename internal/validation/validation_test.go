@@ -0,0 +1,160 @@
+package validation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMileage(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{text: "85000", want: 85000},
+		{text: "120 000 км", want: 120000},
+		{text: "85.000 км", want: 85000},
+		{text: "120,000 miles", want: 193121},
+		{text: "1.200.000 км", want: 1200000},
+		{text: "85 тыс", want: 85000},
+		{text: "85.5 тыс км", want: 85500},
+		{text: "1,5k", want: 1500},
+		{text: "120k miles", want: 193121},
+		{text: "60000 miles", want: 96561},
+		{text: "1.2345 км", want: 1},
+		{text: "85.000 тыс", wantErr: true},
+		{text: "много", wantErr: true},
+		{text: "100 попугаев", wantErr: true},
+		{text: "3000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseMileage(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseMileage(%q) = %d, ожидалась ошибка", tt.text, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseMileage(%q) = %d, %v, ожидалось %d", tt.text, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYear(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{text: "2018", want: 2018},
+		{text: " 2018 г.", want: 2018},
+		{text: "2018г", want: 2018},
+		{text: "1927", want: 1927},
+		{text: "2025", want: 2025},
+		{text: "2026", wantErr: true},
+		{text: "1926", wantErr: true},
+		{text: "18", wantErr: true},
+		{text: "две тысячи", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseYear(tt.text, now)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+				t.Errorf("ParseYear(%q) = %d, %v, ожидалось %d (ошибка: %v)", tt.text, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseEngineVolume(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    float64
+		wantErr bool
+	}{
+		{text: "2.0", want: 2.0},
+		{text: "2,0 л", want: 2.0},
+		{text: "1969 см3", want: 2.0},
+		{text: "1969", want: 2.0},
+		{text: "2.5l", want: 2.5},
+		{text: "1596 cc", want: 1.6},
+		{text: "0.5", wantErr: true},
+		{text: "12", wantErr: true},
+		{text: "2.0 галлона", wantErr: true},
+		{text: "не помню", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseEngineVolume(tt.text)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+				t.Errorf("ParseEngineVolume(%q) = %v, %v, ожидалось %v (ошибка: %v)", tt.text, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "+7 912 345-67-89", want: "+79123456789"},
+		{text: "8 (912) 345-67-89", want: "+79123456789"},
+		{text: "79123456789", want: "+79123456789"},
+		{text: "9123456789", want: "+79123456789"},
+		{text: "+44 20 7946 0958", want: "+442079460958"},
+		{text: "345-67-89", wantErr: true},
+		{text: "+0123456789", wantErr: true},
+		{text: "+7 912 ABC", wantErr: true},
+		{text: "+1234567890123456", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := NormalizePhone(tt.text)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("NormalizePhone(%q) = %q, %v, ожидалось %q (ошибка: %v)", tt.text, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseContact(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantPhone string
+		wantErr   bool
+	}{
+		{text: "@volvo_fan", wantPhone: ""},
+		{text: "+7 912 345-67-89", wantPhone: "+79123456789"},
+		{text: "@abc", wantErr: true},
+		{text: "@имя_клиента", wantErr: true},
+		{text: "позвоните мне", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			phone, err := ParseContact(tt.text)
+			if (err != nil) != tt.wantErr || phone != tt.wantPhone {
+				t.Errorf("ParseContact(%q) = %q, %v, ожидалось %q (ошибка: %v)", tt.text, phone, err, tt.wantPhone, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsUnknown(t *testing.T) {
+	for text, want := range map[string]bool{"Не знаю": true, " незнаю ": true, "-": true, "?": true, "2018": false, "": false} {
+		if got := IsUnknown(text); got != want {
+			t.Errorf("IsUnknown(%q) = %v, ожидалось %v", text, got, want)
+		}
+	}
+}
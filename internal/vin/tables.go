@@ -0,0 +1,53 @@
+package vin
+
+// manufacturers WMI (первые три символа VIN) легковых автомобилей Volvo Cars
+var manufacturers = map[string]string{
+	"YV1": "Volvo Cars, легковой автомобиль (Швеция/Бельгия)",
+	"YV4": "Volvo Cars, кроссовер (Швеция/Бельгия)",
+	"LYV": "Volvo Cars (Китай)",
+	"LVY": "Volvo Cars (Китай)",
+	"7JR": "Volvo Cars (США)",
+	"7JD": "Volvo Cars (США)",
+}
+
+// modelCodes коды моделей (4-5 символы VIN) и соответствующие ID каталога
+var modelCodes = map[string]string{
+	"VS": "s40",
+	"MS": "s40",
+	"VW": "v40",
+	"MV": "v40",
+	"MW": "v50",
+	"MK": "c30",
+	"MC": "c70",
+	"NC": "c70",
+	"RS": "s60",
+	"FS": "s60",
+	"FW": "v60",
+	"TS": "s80",
+	"AS": "s80",
+	"LW": "v70",
+	"SW": "v70",
+	"BW": "v70",
+	"DZ": "xc60",
+	"SZ": "xc70",
+	"BZ": "xc70",
+	"CZ": "xc90",
+	"CM": "xc90",
+	"A2": "xc90",
+	"XZ": "xc40",
+}
+
+// plants коды заводов (11-й символ VIN)
+var plants = map[byte]string{
+	'0': "Кальмар, Швеция",
+	'1': "Торсланда, Швеция",
+	'2': "Гент, Бельгия",
+	'3': "Чэнду, Китай",
+	'5': "Шах-Алам, Малайзия",
+	'A': "Уддевалла, Швеция",
+	'D': "Борн, Нидерланды",
+	'F': "Гент, Бельгия",
+	'J': "Дацин, Китай",
+	'L': "Лутяо, Китай",
+	'G': "Риджвилл, США",
+}
@@ -0,0 +1,99 @@
+// Package vin проверяет VIN и расшифровывает VIN автомобилей Volvo без обращения к внешним сервисам.
+package vin
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Length длина VIN
+const Length = 17
+
+// transliteration числовые значения символов VIN для расчета контрольной цифры
+var transliteration = map[rune]int{
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+// weights веса позиций VIN; 9-я позиция - сама контрольная цифра
+var weights = [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// yearCodes коды модельного года (10-я позиция), повторяются каждые 30 лет начиная с 1980
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// Info результат расшифровки VIN
+type Info struct {
+	VIN          string
+	Manufacturer string // производитель по WMI
+	ModelID      string // ID модели в каталоге, если удалось определить
+	Year         int    // модельный год, 0 если не удалось определить
+	Plant        string // завод-изготовитель
+}
+
+// Normalize приводит VIN к верхнему регистру без пробелов и дефисов
+func Normalize(text string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(text)))
+}
+
+// Validate проверяет длину, допустимые символы и контрольную цифру VIN.
+// Ошибка содержит текст для пользователя.
+func Validate(vin string) error {
+	if len(vin) != Length {
+		return errors.New("VIN должен состоять из 17 символов.")
+	}
+
+	sum := 0
+	for i, r := range vin {
+		value, ok := charValue(r)
+		if !ok {
+			return errors.New("VIN может содержать только латинские буквы (кроме I, O, Q) и цифры.")
+		}
+		sum += value * weights[i]
+	}
+
+	check := byte('0' + sum%11)
+	if sum%11 == 10 {
+		check = 'X'
+	}
+	if vin[8] != check {
+		return errors.New("VIN введен с ошибкой: не сходится контрольная цифра. Проверьте, пожалуйста, VIN.")
+	}
+	return nil
+}
+
+func charValue(r rune) (int, bool) {
+	if r >= '0' && r <= '9' {
+		return int(r - '0'), true
+	}
+	value, ok := transliteration[r]
+	return value, ok
+}
+
+// IsVolvo проверяет, выпущен ли автомобиль Volvo Cars
+func IsVolvo(vin string) bool {
+	_, ok := manufacturers[vin[:3]]
+	return ok
+}
+
+// Decode расшифровывает проверенный VIN автомобиля Volvo.
+// Модельный год выбирается как самый поздний из возможных, но не позже следующего года.
+func Decode(vin string, now time.Time) Info {
+	info := Info{
+		VIN:          vin,
+		Manufacturer: manufacturers[vin[:3]],
+		Plant:        plants[vin[10]],
+		ModelID:      modelCodes[vin[3:5]],
+	}
+
+	if index := strings.IndexByte(yearCodes, vin[9]); index >= 0 {
+		year := 1980 + index
+		for year+30 <= now.Year()+1 {
+			year += 30
+		}
+		info.Year = year
+	}
+
+	return info
+}
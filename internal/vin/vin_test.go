@@ -0,0 +1,82 @@
+package vin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		vin     string
+		wantErr bool
+	}{
+		{"пример из стандарта, контрольная X", "1M8GDM9AXKP042788", false},
+		{"XC90", "YV1CZ91H041088585", false},
+		{"S40", "YV1MS382852123456", false},
+		{"XC40, Китай", "LYVXZBMK4KL123456", false},
+		{"XC90, США", "7JRA22TK9LG012345", false},
+		{"неверная контрольная цифра", "YV1CZ91H141088585", true},
+		{"буква O", "YV1CZ91H04108858O", true},
+		{"строчные буквы", "yv1cz91h041088585", true},
+		{"16 символов", "YV1CZ91H04108858", true},
+		{"18 символов", "YV1CZ91H0410885851", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.vin); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) = %v, ожидалась ошибка: %v", tt.vin, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize(" yv1-cz91h 041088585 "); got != "YV1CZ91H041088585" {
+		t.Errorf("Normalize() = %q", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		vin     string
+		volvo   bool
+		modelID string
+		year    int
+		plant   string
+	}{
+		{"YV1CZ91H041088585", true, "xc90", 2004, "Торсланда, Швеция"},
+		{"YV4A22PK0G1012345", true, "xc90", 2016, "Торсланда, Швеция"},
+		{"YV1MS382852123456", true, "s40", 2005, "Гент, Бельгия"},
+		{"YV1FW40C8D1234567", true, "v60", 2013, "Торсланда, Швеция"},
+		{"LYVXZBMK4KL123456", true, "xc40", 2019, "Лутяо, Китай"},
+		{"7JRA22TK9LG012345", true, "xc90", 2020, "Риджвилл, США"},
+		// Код S повторяется раз в 30 лет: 1995 или 2025, следующий год еще допустим
+		{"YV1CZ91H4S1088585", true, "xc90", 2025, "Торсланда, Швеция"},
+		// Код T: 2026 позже следующего года, значит 1996
+		{"YV1CZ91H2T1088585", true, "xc90", 1996, "Торсланда, Швеция"},
+		// Z не используется как код года
+		{"YV1CZ91H1Z1088585", true, "xc90", 0, "Торсланда, Швеция"},
+		{"WVWZZZ1K4AM123456", false, "", 2010, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vin, func(t *testing.T) {
+			if err := Validate(tt.vin); err != nil {
+				t.Fatalf("Validate(%q) = %v", tt.vin, err)
+			}
+			if got := IsVolvo(tt.vin); got != tt.volvo {
+				t.Errorf("IsVolvo() = %v, ожидалось %v", got, tt.volvo)
+			}
+
+			info := Decode(tt.vin, now)
+			if info.ModelID != tt.modelID || info.Year != tt.year || info.Plant != tt.plant {
+				t.Errorf("Decode() = модель %q, год %d, завод %q; ожидалось %q, %d, %q",
+					info.ModelID, info.Year, info.Plant, tt.modelID, tt.year, tt.plant)
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 	"os/signal"
 	"syscall"
+	"time"
+	_ "time/tzdata"
 
 	"volvomaster/internal/bot"
+	"volvomaster/internal/catalog"
 	"volvomaster/internal/config"
 	"volvomaster/internal/database"
 	"volvomaster/internal/logger"
+	"volvomaster/internal/questionnaire"
 	"volvomaster/internal/services"
 
 	"github.com/joho/godotenv"
@@ -27,33 +32,121 @@ func main() {
 	// Инициализация конфигурации
 	cfg := config.Load()
 
-	// Подключение к MongoDB
-	db, err := database.Connect(cfg.MongoURI)
+	// Контекст отменяется по сигналу завершения, в том числе во время запуска
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	location, err := time.LoadLocation(cfg.Timezone)
 	if err != nil {
-		logger.Fatal("Ошибка подключения к MongoDB: %v", err)
+		logger.Fatal("Неверный часовой пояс %s: %v", cfg.Timezone, err)
 	}
-	defer db.Disconnect(context.Background())
 
-	// Инициализация сервисов
-	dbService := services.NewDatabaseService(db)
+	// Подключение к хранилищу данных
+	var dbService services.Store
+	var mongoService *services.DatabaseService
+
+	switch cfg.DatabaseBackend {
+	case "memory":
+		logger.Info("Данные хранятся в памяти и будут потеряны при перезапуске")
+		dbService = services.NewMemoryStore()
+
+	case "mongo":
+		db, err := database.Connect(cfg.MongoURI)
+		if err != nil {
+			logger.Fatal("Ошибка подключения к MongoDB: %v", err)
+		}
+		defer db.Disconnect(context.Background())
+
+		mongoService = services.NewDatabaseService(db)
+		dbService = mongoService
+
+		// Переводим заявки со старыми статусами на новый жизненный цикл
+		if err := mongoService.MigrateLegacyStatuses(ctx); err != nil {
+			logger.Error("Ошибка миграции статусов заявок: %v", err)
+		}
+
+	default:
+		logger.Fatal("Неизвестное хранилище данных DATABASE_BACKEND=%s", cfg.DatabaseBackend)
+	}
+
+	// Дополняем каталог моделей Volvo
+	if err := dbService.SeedVolvoModels(ctx, catalog.DefaultModels); err != nil {
+		logger.Error("Ошибка заполнения каталога моделей: %v", err)
+	}
+
+	questions, err := loadQuestionnaire(ctx, cfg, mongoService)
+	if err != nil {
+		logger.Fatal("Ошибка загрузки анкеты: %v", err)
+	}
 
 	// Создание и запуск бота
-	telegramBot, err := bot.NewBot(cfg.TelegramToken, dbService)
+	telegramBot, err := bot.NewBot(cfg, dbService, questions)
 	if err != nil {
 		logger.Fatal("Ошибка создания бота: %v", err)
 	}
 
-	// Запуск бота в отдельной горутине
+	// Запуск бота в отдельной горутине. Если бот не может получать обновления,
+	// процесс завершается, а не продолжает работать вхолостую.
+	startErr := make(chan error, 1)
 	go func() {
 		logger.Info("Бот запущен...")
-		telegramBot.Start()
+		startErr <- telegramBot.Start()
 	}()
 
-	// Ожидание сигнала завершения
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Запуск планировщика напоминаний
+	reminderScheduler := bot.NewReminderScheduler(telegramBot, location)
+	go func() {
+		logger.Info("Планировщик напоминаний запущен...")
+		reminderScheduler.Start()
+	}()
+
+	// Запуск проверки незаконченных заявок
+	sessionNudger := bot.NewSessionNudger(telegramBot)
+	go func() {
+		logger.Info("Проверка незаконченных заявок запущена...")
+		sessionNudger.Start()
+	}()
+
+	// Ожидание сигнала завершения или остановки бота из-за ошибки
+	var failure error
+	select {
+	case <-ctx.Done():
+	case err := <-startErr:
+		failure = err
+		if failure == nil {
+			failure = errors.New("бот перестал получать обновления")
+		}
+		logger.Error("Ошибка работы бота: %v", failure)
+	}
+	stop() // повторный сигнал завершит процесс, не дожидаясь остановки
 
+	// Отключение от MongoDB (defer) выполняется только после того, как бот и фоновые задачи
+	// завершат начатую работу или будут прерваны. SHUTDOWN_TIMEOUT - срок на всю остановку.
 	logger.Info("Завершение работы бота...")
-	telegramBot.Stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	reminderScheduler.Stop(shutdownCtx)
+	sessionNudger.Stop(shutdownCtx)
+	telegramBot.Stop(shutdownCtx)
+
+	if failure != nil {
+		logger.Fatal("Бот остановлен из-за ошибки: %v", failure)
+	}
+}
+
+// loadQuestionnaire загружает анкету из файла или MongoDB, по умолчанию - встроенную.
+// dbService равен nil, если бот работает без MongoDB.
+func loadQuestionnaire(ctx context.Context, cfg *config.Config, dbService *services.DatabaseService) (*questionnaire.Definition, error) {
+	switch {
+	case cfg.QuestionnaireFile != "":
+		return questionnaire.LoadFile(cfg.QuestionnaireFile)
+	case cfg.QuestionnaireID != "":
+		if dbService == nil {
+			return nil, fmt.Errorf("анкета %s хранится в MongoDB, а DATABASE_BACKEND=%s", cfg.QuestionnaireID, cfg.DatabaseBackend)
+		}
+		return dbService.GetQuestionnaire(ctx, cfg.QuestionnaireID)
+	default:
+		return questionnaire.Default(), nil
+	}
 }